@@ -0,0 +1,91 @@
+package browser
+
+import (
+	"context"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// Cookies - every cookie in the browser
+func Cookies(ctx context.Context) ([]*network.Cookie, error) {
+	var cookies []*network.Cookie
+	err := chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		cookies, err = network.GetAllCookies().Do(ctx)
+		return err
+	}))
+	return cookies, err
+}
+
+// CookieJar - cookie jar containing the browser's cookies, so that
+// HTTP clients can make requests with the browser's session
+func CookieJar(cookies []*network.Cookie) http.CookieJar {
+	jar, _ := cookiejar.New(nil) // only errors for invalid options
+	for _, c := range cookies {
+		scheme := "http"
+		if c.Secure {
+			scheme = "https"
+		}
+		host := strings.TrimPrefix(c.Domain, ".")
+		u := &url.URL{Scheme: scheme, Host: host, Path: c.Path}
+
+		cookie := &http.Cookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Path:     c.Path,
+			Secure:   c.Secure,
+			HttpOnly: c.HTTPOnly,
+		}
+		// cookies without a leading dot are only sent to the host that set them
+		if strings.HasPrefix(c.Domain, ".") {
+			cookie.Domain = host
+		}
+		if !c.Session {
+			cookie.Expires = time.Unix(int64(c.Expires), 0)
+		}
+		jar.SetCookies(u, []*http.Cookie{cookie})
+	}
+	return jar
+}
+
+// StoreCookies - sets cookies received by an HTTP client in the browser, so
+// that a session updated outside of the browser is not lost
+func StoreCookies(ctx context.Context, u *url.URL, cookies []*http.Cookie) error {
+	var params []*network.CookieParam
+	for _, c := range cookies {
+		param := &network.CookieParam{
+			Name:     c.Name,
+			Value:    c.Value,
+			URL:      u.String(),
+			Domain:   c.Domain,
+			Path:     c.Path,
+			Secure:   c.Secure,
+			HTTPOnly: c.HttpOnly,
+		}
+		var expires time.Time
+		switch {
+		case c.MaxAge < 0: // delete the cookie
+			expires = time.Unix(1, 0)
+		case c.MaxAge > 0:
+			expires = time.Now().Add(time.Duration(c.MaxAge) * time.Second)
+		default:
+			expires = c.Expires
+		}
+		if !expires.IsZero() {
+			e := cdp.TimeSinceEpoch(expires)
+			param.Expires = &e
+		}
+		params = append(params, param)
+	}
+	if len(params) == 0 {
+		return nil
+	}
+	return chromedp.Run(ctx, network.SetCookies(params))
+}
@@ -0,0 +1,77 @@
+package browser
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/chromedp/cdproto/dom"
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// time allowed to capture evidence from a tab
+const evidenceTimeout = 10 * time.Second
+
+// maximum height of screenshots, the largest texture Chrome can capture
+const maxScreenshotHeight = 16384
+
+// Evidence - state of the page loaded in a tab
+type Evidence struct {
+	URL        string
+	DOM        string
+	Screenshot []byte // PNG
+}
+
+// CaptureEvidence - full page screenshot, URL and DOM of the page loaded in the tab.
+// Anything captured before an error is returned alongside it
+func CaptureEvidence(ctx context.Context) (*Evidence, error) {
+	timeoutContext, cancel := context.WithTimeout(ctx, evidenceTimeout)
+	defer cancel()
+
+	e := &Evidence{}
+	err := chromedp.Run(timeoutContext,
+		chromedp.Location(&e.URL),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			node, err := dom.GetDocument().Do(ctx)
+			if err != nil {
+				return err
+			}
+			e.DOM, err = dom.GetOuterHTML().WithNodeID(node.NodeID).Do(ctx)
+			return err
+		}),
+		fullScreenshot(&e.Screenshot),
+	)
+	return e, err
+}
+
+// screenshot of the whole page rather than the visible area, by resizing
+// the viewport to the size of the page while the screenshot is taken
+func fullScreenshot(res *[]byte) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		_, _, contentSize, err := page.GetLayoutMetrics().Do(ctx)
+		if err != nil {
+			return err
+		}
+		width := int64(math.Ceil(contentSize.Width))
+		height := int64(math.Min(math.Ceil(contentSize.Height), maxScreenshotHeight))
+
+		err = emulation.SetDeviceMetricsOverride(width, height, 1, false).Do(ctx)
+		if err != nil {
+			return err
+		}
+		defer emulation.ClearDeviceMetricsOverride().Do(ctx)
+
+		*res, err = page.CaptureScreenshot().
+			WithFormat(page.CaptureScreenshotFormatPng).
+			WithClip(&page.Viewport{
+				X:      contentSize.X,
+				Y:      contentSize.Y,
+				Width:  float64(width),
+				Height: float64(height),
+				Scale:  1,
+			}).Do(ctx)
+		return err
+	})
+}
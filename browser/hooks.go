@@ -0,0 +1,97 @@
+package browser
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+	"github.com/morganc3/KOAuth/config"
+)
+
+// maximum number of times hooks run in a single flow, so that a hook
+// which does not leave its page can not loop forever
+const maxHookRuns = 10
+
+// HookRuns - number of times hooks ran in a tab since the last Reset
+type HookRuns struct {
+	mu   sync.Mutex
+	runs int
+}
+
+// WatchHooks - runs the actions of the first hook matching the URL of each page
+// loaded in the tab. Actions run in the background, so that the caller can keep
+// waiting for the redirect. Each run of actions is bounded by the timeout, and
+// hooks stop running after maxHookRuns until Reset is called
+func WatchHooks(ctx context.Context, timeout time.Duration) *HookRuns {
+	r := &HookRuns{}
+	hooks := config.OAuthConfig.Hooks
+	if len(hooks) == 0 {
+		return r
+	}
+
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		nav, ok := ev.(*page.EventFrameNavigated)
+		// only navigations of the main frame
+		if !ok || nav.Frame.ParentID != "" {
+			return
+		}
+		for i := range hooks {
+			h := &hooks[i]
+			if !h.Matches(nav.Frame.URL) {
+				continue
+			}
+			if r.exceeded() {
+				return
+			}
+			go runHook(ctx, timeout, h)
+			return
+		}
+	})
+	return r
+}
+
+// counts a run, and reports whether it is over the maximum
+func (r *HookRuns) exceeded() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.runs++
+	return r.runs > maxHookRuns
+}
+
+// Reset - allows hooks to run again, for the next flow in the tab
+func (r *HookRuns) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.runs = 0
+}
+
+func runHook(ctx context.Context, timeout time.Duration, h *config.Hook) {
+	timeoutContext, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := chromedp.Run(timeoutContext, hookActions(h)...)
+	if err != nil && ctx.Err() == nil {
+		slog.Warn("Hook failed", "urlPattern", h.URLPattern, "err", err)
+	}
+}
+
+// hookActions - chromedp actions for the actions of a hook
+func hookActions(h *config.Hook) []chromedp.Action {
+	var actions []chromedp.Action
+	for _, a := range h.Actions {
+		switch a.Action {
+		case config.HookActionClick:
+			actions = append(actions, chromedp.Click(a.Selector, chromedp.ByQuery))
+		case config.HookActionSendKeys:
+			actions = append(actions, chromedp.SendKeys(a.Selector, a.Value, chromedp.ByQuery))
+		case config.HookActionWait:
+			actions = append(actions, chromedp.WaitVisible(a.Selector, chromedp.ByQuery))
+		case config.HookActionSleep:
+			actions = append(actions, chromedp.Sleep(time.Duration(a.Seconds)*time.Second))
+		}
+	}
+	return actions
+}
@@ -0,0 +1,19 @@
+package browser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHookRuns(t *testing.T) {
+	r := &HookRuns{}
+	for i := 0; i < maxHookRuns; i++ {
+		assert.False(t, r.exceeded())
+	}
+	assert.True(t, r.exceeded())
+
+	// the next flow in the tab can run hooks again
+	r.Reset()
+	assert.False(t, r.exceeded())
+}
@@ -0,0 +1,90 @@
+package browser
+
+import (
+	"context"
+	"sync"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// Hop - a document requested in a tab, and the status code it was served with.
+// Status is 0 if no response was received, such as for the redirect_uri
+type Hop struct {
+	URL    string `json:"url"`
+	Status int64  `json:"status,omitempty"`
+
+	requested string // URL without the fragment, as in responses
+}
+
+// RedirectChain - documents requested in a tab, in the order they were requested
+type RedirectChain struct {
+	mu      sync.Mutex
+	stopped bool
+	hops    []Hop
+}
+
+// WatchRedirectChain - records each document requested in the tab, including
+// the redirects between them, until Stop is called
+func WatchRedirectChain(ctx context.Context) *RedirectChain {
+	r := &RedirectChain{}
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.stopped {
+			return
+		}
+
+		switch e := ev.(type) {
+		case *network.EventRequestWillBeSent:
+			if e.Type != network.ResourceTypeDocument {
+				return
+			}
+			// the response to the previous request was a redirect
+			if e.RedirectResponse != nil {
+				r.setStatus(e.RedirectResponse.URL, e.RedirectResponse.Status)
+			}
+			r.hops = append(r.hops, Hop{
+				URL:       e.Request.URL + e.Request.URLFragment,
+				requested: e.Request.URL,
+			})
+		case *network.EventResponseReceived:
+			if e.Type == network.ResourceTypeDocument {
+				r.setStatus(e.Response.URL, e.Response.Status)
+			}
+		}
+	})
+	return r
+}
+
+// sets the status of the last request of the URL without a status
+func (r *RedirectChain) setStatus(u string, status int64) {
+	for i := len(r.hops) - 1; i >= 0; i-- {
+		if r.hops[i].requested == u && r.hops[i].Status == 0 {
+			r.hops[i].Status = status
+			return
+		}
+	}
+}
+
+// Stop - stop recording, so navigations of later flows in the same tab are not recorded
+func (r *RedirectChain) Stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stopped = true
+}
+
+// Restart - forgets the documents recorded and records again, for the next flow in the tab
+func (r *RedirectChain) Restart() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hops = nil
+	r.stopped = false
+}
+
+// Hops - documents requested so far
+func (r *RedirectChain) Hops() []Hop {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Hop(nil), r.hops...)
+}
@@ -5,7 +5,6 @@ import (
 	"time"
 
 	"github.com/chromedp/chromedp"
-	"github.com/morganc3/KOAuth/config"
 )
 
 // ChromeExecContext - Original parent chrome tab context
@@ -14,22 +13,21 @@ var ChromeExecContext context.Context
 // ChromeExecContextCancel - Original parent chrome tab context cancel function
 var ChromeExecContextCancel context.CancelFunc
 
-// RunWithTimeOut - run chromedp actions with a specified timeout
-func RunWithTimeOut(ctx *context.Context, timeout time.Duration, actions []chromedp.Action) (context.Context, error) {
-	timeoutContext, _ := context.WithTimeout(*ctx, timeout*time.Second)
-	// defer timeoutCancel()
-	return timeoutContext, chromedp.Run(timeoutContext, actions...)
+// RunWithTimeOut - run chromedp actions with a specified timeout. The returned context
+// is done when the timeout expires, and must be cancelled once it is no longer used
+func RunWithTimeOut(ctx *context.Context, timeout time.Duration, actions []chromedp.Action) (context.Context, context.CancelFunc, error) {
+	timeoutContext, cancel := context.WithTimeout(*ctx, timeout)
+	return timeoutContext, cancel, chromedp.Run(timeoutContext, actions...)
 }
 
-// InitChromeSession - initialize chrome session, setting
-// options from CLI
-func InitChromeSession() context.CancelFunc {
+// InitChromeSession - initialize chrome session, with
+// the User-Agent and proxy provided on the command line
+func InitChromeSession(userAgent, proxy string) context.CancelFunc {
 	var chromeOpts []chromedp.ExecAllocatorOption
 	headlessFlag := chromedp.Flag("headless", false)
-	userAgentFlag := chromedp.UserAgent(config.GetOpt(config.FlagUserAgent))
+	userAgentFlag := chromedp.UserAgent(userAgent)
 	chromeOpts = append(chromedp.DefaultExecAllocatorOptions[:], headlessFlag, userAgentFlag)
 
-	proxy := config.GetOpt(config.FlagProxy)
 	if proxy != "" {
 		// Be sure you trust your proxy server if you choose this option
 		ignoreCerts := chromedp.Flag("ignore-certificate-errors", true)
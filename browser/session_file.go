@@ -0,0 +1,174 @@
+package browser
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/domstorage"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// SessionKeyEnv - environment variable containing the base64 encoded
+// 256 bit key used to encrypt session files
+const SessionKeyEnv = "KOAUTH_SESSION_KEY"
+
+// SessionState - authenticated browser state, saved after
+// authenticating so that later runs can skip authentication
+type SessionState struct {
+	Saved   time.Time         `json:"saved"`
+	Cookies []*network.Cookie `json:"cookies"`
+	// local storage items keyed by origin
+	LocalStorage map[string][]domstorage.Item `json:"localStorage"`
+}
+
+// CaptureSession - gets every cookie in the browser, and the
+// local storage of the provided origins
+func CaptureSession(ctx context.Context, origins []string) (*SessionState, error) {
+	state := &SessionState{
+		Saved:        time.Now(),
+		LocalStorage: make(map[string][]domstorage.Item),
+	}
+	err := chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		state.Cookies, err = network.GetAllCookies().Do(ctx)
+		if err != nil {
+			return err
+		}
+		err = domstorage.Enable().Do(ctx)
+		if err != nil {
+			return err
+		}
+		for _, origin := range origins {
+			items, err := domstorage.GetDOMStorageItems(localStorageID(origin)).Do(ctx)
+			if err != nil {
+				slog.Warn("Could not get local storage", "origin", origin, "err", err)
+				continue
+			}
+			state.LocalStorage[origin] = items
+		}
+		return nil
+	}))
+	return state, err
+}
+
+// RestoreSession - sets the cookies and local storage of a saved session. Local
+// storage can only be set for an origin loaded in the tab, so each origin is visited
+func RestoreSession(ctx context.Context, state *SessionState) error {
+	var cookies []*network.CookieParam
+	for _, c := range state.Cookies {
+		param := &network.CookieParam{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			Secure:   c.Secure,
+			HTTPOnly: c.HTTPOnly,
+			SameSite: c.SameSite,
+			Priority: c.Priority,
+		}
+		if !c.Session {
+			expires := cdp.TimeSinceEpoch(time.Unix(int64(c.Expires), 0))
+			param.Expires = &expires
+		}
+		cookies = append(cookies, param)
+	}
+
+	actions := []chromedp.Action{network.SetCookies(cookies), domstorage.Enable()}
+	for origin, items := range state.LocalStorage {
+		if len(items) == 0 {
+			continue
+		}
+		actions = append(actions, chromedp.Navigate(origin))
+		for _, item := range items {
+			if len(item) != 2 {
+				continue
+			}
+			actions = append(actions, domstorage.SetDOMStorageItem(localStorageID(origin), item[0], item[1]))
+		}
+	}
+	return chromedp.Run(ctx, actions...)
+}
+
+func localStorageID(origin string) *domstorage.StorageID {
+	return &domstorage.StorageID{SecurityOrigin: origin, IsLocalStorage: true}
+}
+
+// SessionKey - reads the session file encryption key from the environment
+func SessionKey() ([]byte, error) {
+	encoded := os.Getenv(SessionKeyEnv)
+	if encoded == "" {
+		return nil, fmt.Errorf("%s must be set to use a session file", SessionKeyEnv)
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not valid base64: %s", SessionKeyEnv, err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("%s must be a 256 bit key", SessionKeyEnv)
+	}
+	return key, nil
+}
+
+// SaveSessionFile - encrypts the session with AES-GCM and writes it to a
+// file only readable by the current user
+func SaveSessionFile(path string, state *SessionState, key []byte) error {
+	plaintext, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+	ciphertext := gcm.Seal(nonce, nonce, plaintext, nil)
+	return ioutil.WriteFile(path, ciphertext, 0600)
+}
+
+// LoadSessionFile - reads and decrypts a session file
+func LoadSessionFile(path string, key []byte) (*SessionState, error) {
+	ciphertext, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, errors.New("Session file is too short")
+	}
+	nonce, ciphertext := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, errors.New("Could not decrypt session file, the key may be wrong")
+	}
+
+	var state SessionState
+	err = json.Unmarshal(plaintext, &state)
+	return &state, err
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,39 @@
+package browser
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chromedp/cdproto/domstorage"
+	"github.com/chromedp/cdproto/network"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "koauth")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "session")
+
+	key := make([]byte, 32)
+	state := &SessionState{
+		Cookies:      []*network.Cookie{{Name: "sid", Value: "secret", Domain: "example.com", Priority: network.CookiePriorityMedium}},
+		LocalStorage: map[string][]domstorage.Item{"https://example.com": {{"k", "v"}}},
+	}
+	assert.Nil(t, SaveSessionFile(path, state, key))
+
+	raw, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	assert.NotContains(t, string(raw), "secret")
+
+	loaded, err := LoadSessionFile(path, key)
+	assert.Nil(t, err)
+	assert.Equal(t, "secret", loaded.Cookies[0].Value)
+	assert.Equal(t, domstorage.Item{"k", "v"}, loaded.LocalStorage["https://example.com"][0])
+
+	key[0] = 1
+	_, err = LoadSessionFile(path, key)
+	assert.NotNil(t, err)
+}
@@ -4,18 +4,34 @@ import (
 	"context"
 	"log"
 	"net/url"
+	"sync"
+	"time"
 
 	"github.com/chromedp/cdproto/dom"
 	"github.com/chromedp/cdproto/network"
 	"github.com/chromedp/chromedp"
 )
 
-// WaitRedirect - Wait until we get a redirect to a URL that contains our redirect URI's host
+// Redirect - request made to the redirect URI. Form contains the
+// parameters of a POST request, such as for response_mode=form_post
+type Redirect struct {
+	URL  *url.URL
+	Form url.Values
+}
+
+// URLMatcher - matches URLs which are the redirect URI
+type URLMatcher interface {
+	Match(u *url.URL) bool
+}
+
+// WaitRedirect - Wait until we get a redirect to a URL the matcher matches
 // There is no easy way to do this with the chromedp API's, so we
 // watch events until we get one that is a EventRequestWillBeSent type with
-// a URL of our redirectURI
-func WaitRedirect(ctx context.Context, host, path string) <-chan *url.URL {
-	ch := make(chan *url.URL, 1)
+// a URL of our redirectURI. Only the first redirect is sent, as listeners can not be
+// removed from a tab and later flows in the same tab are redirected to the same URL
+func WaitRedirect(ctx context.Context, matcher URLMatcher) <-chan *Redirect {
+	ch := make(chan *Redirect, 1)
+	var once sync.Once
 	chromedp.ListenTarget(ctx, func(ev interface{}) {
 		redirect, ok := ev.(*network.EventRequestWillBeSent)
 		if ok {
@@ -28,14 +44,16 @@ func WaitRedirect(ctx context.Context, host, path string) <-chan *url.URL {
 			}
 
 			// if we are being redirected to the provided redirectURL
-			if redirectURL.Host == host && redirectURL.Path == path {
-				select {
-				case <-ctx.Done():
-					return
-				case ch <- redirectURL:
-					close(ch)
-					return
+			if matcher.Match(redirectURL) {
+				r := &Redirect{URL: redirectURL}
+				if redirect.Request.Method == "POST" {
+					r.Form, _ = url.ParseQuery(redirect.Request.PostData)
 				}
+				// the channel is buffered, so sending never blocks
+				once.Do(func() {
+					ch <- r
+					close(ch)
+				})
 			}
 		}
 	})
@@ -100,3 +118,61 @@ func getFullResponse(chromeContext context.Context, url string, requestHeaders m
 			return err
 		})}
 }
+
+// CurrentURL - URL of the page currently loaded in the tab
+func CurrentURL(ctx context.Context) *url.URL {
+	timeoutContext, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	var location string
+	err := chromedp.Run(timeoutContext, chromedp.Location(&location))
+	if err != nil {
+		return nil
+	}
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil
+	}
+	return u
+}
+
+// PageText - text of the page currently loaded in the tab, empty if it could not be read
+func PageText(ctx context.Context) string {
+	timeoutContext, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	var text string
+	err := chromedp.Run(timeoutContext, chromedp.Evaluate(`document.body ? document.body.innerText : ""`, &text))
+	if err != nil {
+		return ""
+	}
+	return text
+}
+
+// DocumentStatuses - HTTP status codes of documents loaded in a tab, keyed by URL
+type DocumentStatuses struct {
+	mu       sync.Mutex
+	statuses map[string]int64
+}
+
+// WatchDocumentStatuses - records the status code of each document loaded in the tab
+func WatchDocumentStatuses(ctx context.Context) *DocumentStatuses {
+	d := &DocumentStatuses{statuses: make(map[string]int64)}
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		resp, ok := ev.(*network.EventResponseReceived)
+		if !ok || resp.Type != network.ResourceTypeDocument {
+			return
+		}
+		d.mu.Lock()
+		d.statuses[resp.Response.URL] = resp.Response.Status
+		d.mu.Unlock()
+	})
+	return d
+}
+
+// Status - status code of the document loaded from the URL, 0 if none was loaded
+func (d *DocumentStatuses) Status(u string) int64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.statuses[u]
+}
@@ -0,0 +1,92 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// TraceEvent - a CDP event received from a tab
+type TraceEvent struct {
+	Time   time.Time   `json:"time"`
+	Event  string      `json:"event"` // such as "network.EventRequestWillBeSent"
+	Params interface{} `json:"params"`
+}
+
+// Trace - writes the CDP events of tabs to a writer, as a JSON object on each line
+type Trace struct {
+	mu      sync.Mutex
+	enc     *json.Encoder
+	stopped bool
+}
+
+// NewTrace - trace writing events to w
+func NewTrace(w io.Writer) *Trace {
+	return &Trace{enc: json.NewEncoder(w)}
+}
+
+// Watch - writes the events of the tab until Stop is called. Listeners can
+// not be removed from a tab, so a tab should only be watched by one trace
+func (t *Trace) Watch(ctx context.Context) {
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		t.write(TraceEvent{Time: time.Now(), Event: eventName(ev), Params: ev})
+	})
+}
+
+// Stop - stops writing events, after which the writer can be closed
+func (t *Trace) Stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stopped = true
+}
+
+func (t *Trace) write(e TraceEvent) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stopped {
+		return
+	}
+	if err := t.enc.Encode(e); err != nil {
+		// stop after the first error, rather than logging one for every event
+		slog.Warn("Could not write CDP event to trace", "err", err)
+		t.stopped = true
+	}
+}
+
+// name of the package and type of an event, such as "page.EventFrameNavigated"
+func eventName(ev interface{}) string {
+	return strings.TrimPrefix(fmt.Sprintf("%T", ev), "*")
+}
+
+// LogNavigations - logs the documents requested and navigations of the main
+// frame of the tab at debug level, if the logger is enabled for it
+func LogNavigations(ctx context.Context, logger *slog.Logger) {
+	if !logger.Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		switch e := ev.(type) {
+		case *network.EventRequestWillBeSent:
+			if e.Type == network.ResourceTypeDocument {
+				logger.Debug("Document requested", "method", e.Request.Method, "url", e.Request.URL+e.Request.URLFragment)
+			}
+		case *network.EventResponseReceived:
+			if e.Type == network.ResourceTypeDocument {
+				logger.Debug("Document received", "status", e.Response.Status, "url", e.Response.URL)
+			}
+		case *page.EventFrameNavigated:
+			if e.Frame.ParentID == "" {
+				logger.Debug("Navigated", "url", e.Frame.URL+e.Frame.URLFragment)
+			}
+		}
+	})
+}
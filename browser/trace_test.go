@@ -0,0 +1,34 @@
+package browser
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/page"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrace(t *testing.T) {
+	var b bytes.Buffer
+	tr := NewTrace(&b)
+	ev := &page.EventFrameNavigated{Frame: &cdp.Frame{URL: "https://idp.example.com/authorize"}}
+	tr.write(TraceEvent{Time: time.Now(), Event: eventName(ev), Params: ev})
+	tr.Stop()
+	tr.write(TraceEvent{Time: time.Now(), Event: eventName(ev), Params: ev})
+
+	scanner := bufio.NewScanner(&b)
+	var lines []map[string]interface{}
+	for scanner.Scan() {
+		var line map[string]interface{}
+		assert.Nil(t, json.Unmarshal(scanner.Bytes(), &line))
+		lines = append(lines, line)
+	}
+	assert.Len(t, lines, 1)
+	assert.Equal(t, "page.EventFrameNavigated", lines[0]["event"])
+	frame := lines[0]["params"].(map[string]interface{})["frame"].(map[string]interface{})
+	assert.Equal(t, "https://idp.example.com/authorize", frame["url"])
+}
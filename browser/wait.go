@@ -0,0 +1,125 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// how often wait conditions are evaluated
+const waitPollInterval = 250 * time.Millisecond
+
+// time without requests after which the network is idle
+const networkIdleTime = 500 * time.Millisecond
+
+// WaitCondition - state of the tab to wait for. Every condition provided must be met
+type WaitCondition struct {
+	// CSS selector of an element which must be in the page
+	Selector string `json:"selector,omitempty"`
+	// regular expression the URL of the page must match
+	URL string `json:"url,omitempty"`
+	// no requests may be in progress, and none may have started within networkIdleTime
+	NetworkIdle bool `json:"networkIdle,omitempty"`
+}
+
+// Validate - returns an error if the URL pattern is not a valid regular expression
+func (w *WaitCondition) Validate() error {
+	if w.URL == "" {
+		return nil
+	}
+	_, err := regexp.Compile(w.URL)
+	return err
+}
+
+// WaitFor - returns a channel which is closed once every condition is met in the
+// tab. Nothing is sent if the context is done first
+func WaitFor(ctx context.Context, w WaitCondition) <-chan struct{} {
+	ch := make(chan struct{})
+	var urlPattern *regexp.Regexp
+	if w.URL != "" {
+		urlPattern = regexp.MustCompile(w.URL)
+	}
+	var idle *networkIdle
+	if w.NetworkIdle {
+		idle = watchNetworkIdle(ctx)
+	}
+
+	go func() {
+		ticker := time.NewTicker(waitPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if urlPattern != nil && !urlMatches(ctx, urlPattern) {
+				continue
+			}
+			if w.Selector != "" && !elementExists(ctx, w.Selector) {
+				continue
+			}
+			if idle != nil && !idle.idle() {
+				continue
+			}
+			close(ch)
+			return
+		}
+	}()
+	return ch
+}
+
+func urlMatches(ctx context.Context, pattern *regexp.Regexp) bool {
+	u := CurrentURL(ctx)
+	return u != nil && pattern.MatchString(u.String())
+}
+
+// true if an element matching the selector is in the page. Errors,
+// such as while the page is navigating, are treated as not found
+func elementExists(ctx context.Context, selector string) bool {
+	s, err := json.Marshal(selector)
+	if err != nil {
+		return false
+	}
+	var exists bool
+	err = chromedp.Run(ctx, chromedp.Evaluate("document.querySelector("+string(s)+") !== null", &exists))
+	return err == nil && exists
+}
+
+// networkIdle - requests in progress in a tab, and when the last one started or ended
+type networkIdle struct {
+	mu       sync.Mutex
+	inflight map[network.RequestID]bool
+	last     time.Time
+}
+
+func watchNetworkIdle(ctx context.Context) *networkIdle {
+	n := &networkIdle{inflight: make(map[network.RequestID]bool), last: time.Now()}
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		switch e := ev.(type) {
+		case *network.EventRequestWillBeSent:
+			n.inflight[e.RequestID] = true
+		case *network.EventLoadingFinished:
+			delete(n.inflight, e.RequestID)
+		case *network.EventLoadingFailed:
+			delete(n.inflight, e.RequestID)
+		default:
+			return
+		}
+		n.last = time.Now()
+	})
+	return n
+}
+
+func (n *networkIdle) idle() bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return len(n.inflight) == 0 && time.Since(n.last) >= networkIdleTime
+}
@@ -0,0 +1,26 @@
+package browser
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitConditionValidate(t *testing.T) {
+	assert.Nil(t, (&WaitCondition{URL: `^https://idp\.example\.com/mfa`}).Validate())
+	assert.NotNil(t, (&WaitCondition{URL: `(`}).Validate())
+}
+
+func TestNetworkIdle(t *testing.T) {
+	n := &networkIdle{inflight: map[network.RequestID]bool{"1": true}}
+	assert.False(t, n.idle())
+
+	delete(n.inflight, "1")
+	n.last = time.Now()
+	assert.False(t, n.idle())
+
+	n.last = time.Now().Add(-networkIdleTime)
+	assert.True(t, n.idle())
+}
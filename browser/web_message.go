@@ -0,0 +1,66 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+)
+
+// name of the function pages of the tab call to report the messages they post
+const webMessageBinding = "koauthWebMessage"
+
+// wraps postMessage in every document of the tab. A page delivering an authorization
+// response with response_mode=web_message posts it to the window which opened it, or
+// its parent. A tab has neither, so its own window is used instead, and the message is
+// reported before postMessage drops it for not being addressed to the page's origin
+const webMessageScript = `(function() {
+	var post = window.postMessage;
+	window.postMessage = function(message, targetOrigin) {
+		try {
+			window.` + webMessageBinding + `(JSON.stringify({data: message, targetOrigin: String(targetOrigin)}));
+		} catch (e) {}
+		return post.apply(this, arguments);
+	};
+})();`
+
+// WebMessage - a message posted by a page in the tab
+type WebMessage struct {
+	Data         json.RawMessage `json:"data"`
+	TargetOrigin string          `json:"targetOrigin"`
+}
+
+// WaitWebMessage - sends the first message posted by a page in the tab which the
+// match function accepts. Must be called before navigating to the page posting it
+func WaitWebMessage(ctx context.Context, match func(*WebMessage) bool) (<-chan *WebMessage, error) {
+	ch := make(chan *WebMessage, 1)
+	var once sync.Once
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		called, ok := ev.(*runtime.EventBindingCalled)
+		if !ok || called.Name != webMessageBinding {
+			return
+		}
+		var m WebMessage
+		if json.Unmarshal([]byte(called.Payload), &m) != nil || !match(&m) {
+			return
+		}
+		// the channel is buffered, so sending never blocks
+		once.Do(func() {
+			ch <- &m
+			close(ch)
+		})
+	})
+
+	err := chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		err := runtime.AddBinding(webMessageBinding).Do(ctx)
+		if err != nil {
+			return err
+		}
+		_, err = page.AddScriptToEvaluateOnNewDocument(webMessageScript).Do(ctx)
+		return err
+	}))
+	return ch, err
+}
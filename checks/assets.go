@@ -0,0 +1,25 @@
+package checks
+
+import (
+	"embed" // embeds the default checks, rule packs and report template
+)
+
+// defaultReportTemplate - HTML report template used when --report-template is not provided
+//
+//go:embed assets/report.html
+var defaultReportTemplate string
+
+// defaultChecks - checks run when --checks is not provided
+//
+//go:embed rules/checks.json
+var defaultChecks string
+
+// nativeAppChecks - checks for native apps, which are added to the default checks
+//
+//go:embed rules/native-app.json
+var nativeAppChecks string
+
+// packFiles - manifests of the built-in rule packs
+//
+//go:embed packs/*.json
+var packFiles embed.FS
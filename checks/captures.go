@@ -0,0 +1,175 @@
+package checks
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/morganc3/KOAuth/oauth"
+)
+
+// Capture sources, as defined in provided JSON check structure
+const (
+	captureFromRedirect = "redirect" // query or fragment parameter of the URL we were redirected to
+	captureFromToken    = "token"    // field of the token endpoint response
+	captureFromHeader   = "header"   // header of the token endpoint response
+	captureFromDevice   = "device"   // field of the device authorization response
+	captureFromPushed   = "pushed"   // field of the pushed authorization response
+	captureFromDPoP     = "dpop"     // "jti" claim of the last DPoP proof sent, or the whole "proof"
+)
+
+// References to captured values take the form ${name}. Mustache
+// templating is rendered before checks are parsed, so this syntax
+// must not overlap with it.
+var captureReference = regexp.MustCompile(`\$\{([A-Za-z0-9_.-]+)\}`)
+
+// missingCaptureError - a step referenced a value which was not captured by an earlier
+// step, such as a refresh_token the server did not issue. The step can not be run
+type missingCaptureError struct {
+	name string
+}
+
+func (e *missingCaptureError) Error() string {
+	return fmt.Sprintf("Value %s was not captured by an earlier step", e.name)
+}
+
+// capture - a named value taken from the outcome of a step, which
+// later steps in the same check can reference
+type capture struct {
+	Name string `json:"name"`
+	From string `json:"from"`
+	Key  string `json:"key"`
+}
+
+// stores the values defined in the step's captures, if they are present
+func (s *step) storeCaptures(captured map[string]string) {
+	for _, cp := range s.Captures {
+		v := cp.value(s.FlowInstance)
+		if v == "" {
+			continue
+		}
+		captured[cp.Name] = v
+		if s.captured == nil {
+			s.captured = make(map[string]string)
+		}
+		s.captured[cp.Name] = v
+	}
+}
+
+// gets the value of a capture from a completed flow instance
+func (cp capture) value(fi *oauth.FlowInstance) string {
+	switch cp.From {
+	case captureFromRedirect:
+		if fi.RedirectedToURL == nil {
+			return ""
+		}
+		if v := fi.ResponseParameter(cp.Key); v != "" {
+			return v
+		}
+		if v := oauth.GetQueryParameterFirst(fi.RedirectedToURL, cp.Key); v != "" {
+			return v
+		}
+		return oauth.GetFragmentParameterFirst(fi.RedirectedToURL, cp.Key)
+	case captureFromToken:
+		tok := fi.Token
+		if tok == nil {
+			return ""
+		}
+		switch cp.Key {
+		case oauth.AccessTokenParam:
+			return tok.AccessToken
+		case oauth.RefreshTokenParam:
+			return tok.RefreshToken
+		case oauth.TokenTypeParam:
+			return tok.TokenType
+		}
+		if v := tok.Extra(cp.Key); v != nil {
+			return fmt.Sprint(v)
+		}
+	case captureFromHeader:
+		if fi.ExchangeRequest == nil || fi.ExchangeRequest.Response == nil {
+			return ""
+		}
+		return fi.ExchangeRequest.Response.Header.Get(cp.Key)
+	case captureFromDevice:
+		da := fi.DeviceAuthorization
+		if da == nil {
+			return ""
+		}
+		switch cp.Key {
+		case oauth.DeviceCodeParam:
+			return da.DeviceCode
+		case oauth.UserCodeParam:
+			return da.UserCode
+		case "verification_uri":
+			return da.VerificationURI
+		case "verification_uri_complete":
+			return da.VerificationURIComplete
+		}
+	case captureFromPushed:
+		pa := fi.PushedAuthorization
+		if pa == nil {
+			return ""
+		}
+		switch cp.Key {
+		case oauth.RequestURIParam:
+			return pa.RequestURI
+		case oauth.ExpiresInParam:
+			return fmt.Sprint(pa.ExpiresIn)
+		}
+	case captureFromDPoP:
+		switch cp.Key {
+		case "jti":
+			return fi.DPoPJTI
+		case "proof":
+			return fi.DPoPLastProof
+		}
+	}
+	return ""
+}
+
+// substituteCaptures - returns a copy of the provided parameters with references
+// to captured values replaced. The original parameters are left untouched so
+// that the check definition can be run again.
+func substituteCaptures(pm map[string][]string, captured map[string]string) (map[string][]string, error) {
+	if pm == nil {
+		return nil, nil
+	}
+	ret := make(map[string][]string, len(pm))
+	for key, values := range pm {
+		for _, v := range values {
+			sub, err := substituteCapture(v, captured)
+			if err != nil {
+				return nil, err
+			}
+			ret[key] = append(ret[key], sub)
+		}
+	}
+	return ret, nil
+}
+
+// replaces references to captured values in a single string
+func substituteCapture(v string, captured map[string]string) (string, error) {
+	var err error
+	ret := captureReference.ReplaceAllStringFunc(v, func(ref string) string {
+		name := captureReference.FindStringSubmatch(ref)[1]
+		val, ok := captured[name]
+		if !ok {
+			err = &missingCaptureError{name: name}
+			return ref
+		}
+		return val
+	})
+	return ret, err
+}
+
+// returns a copy of the DPoP options with references to captured values replaced
+func substituteDPoPOptions(opts oauth.DPoPOptions, captured map[string]string) (oauth.DPoPOptions, error) {
+	var err error
+	for _, v := range []*string{&opts.HTU, &opts.HTM, &opts.JTI, &opts.Proof} {
+		*v, err = substituteCapture(*v, captured)
+		if err != nil {
+			return opts, err
+		}
+	}
+	return opts, nil
+}
@@ -0,0 +1,49 @@
+package checks
+
+import (
+	"testing"
+
+	"github.com/morganc3/KOAuth/oauth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubstituteCaptures(t *testing.T) {
+	captured := map[string]string{"code": "abc123", "state": "xyz"}
+
+	v, err := substituteCapture("${code}", captured)
+	assert.Nil(t, err)
+	assert.Equal(t, "abc123", v)
+
+	v, err = substituteCapture("https://example.com/cb?state=${state}&code=${code}", captured)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://example.com/cb?state=xyz&code=abc123", v)
+
+	v, err = substituteCapture("no references", captured)
+	assert.Nil(t, err)
+	assert.Equal(t, "no references", v)
+
+	_, err = substituteCapture("${missing}", captured)
+	assert.NotNil(t, err)
+
+	params := map[string][]string{"code": {"${code}"}}
+	sub, err := substituteCaptures(params, captured)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"abc123"}, sub["code"])
+	assert.Equal(t, []string{"${code}"}, params["code"]) // original untouched
+}
+
+func TestMissingCapture(t *testing.T) {
+	// a step referencing a value no earlier step captured makes the check
+	// inconclusive, whichever outcome the step was required to have
+	for _, required := range []string{outcomeFail, outcomeSucceed} {
+		c := &check{CheckName: "revoked-refresh-token-usable", Steps: []step{{
+			FlowType:        oauth.FlowAuthorizationCode,
+			AuthURLParams:   map[string][]string{"refresh_token": {"${refresh_token}"}},
+			RequiredOutcome: required,
+			FlowInstance:    &oauth.FlowInstance{},
+		}}}
+		assert.Equal(t, warn, c.runCheck())
+		assert.Equal(t, "Step 1 was not run: Value refresh_token was not captured by an earlier step", c.errorMessage)
+		assert.Equal(t, 1, c.Steps[0].attempts)
+	}
+}
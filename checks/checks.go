@@ -5,6 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"net/url"
+	"strings"
+	"sync"
 
 	"github.com/chromedp/chromedp"
 	"github.com/morganc3/KOAuth/config"
@@ -33,17 +36,22 @@ var checksList []*check        // List of normal or custom checks
 var supportChecksList []*check // List of "support" checks
 
 type check struct {
-	CheckName   string `json:"name"`
-	RiskRating  string `json:"risk"`
-	Description string `json:"description"`
-	SkipReason  string `json:"skipReason,omitempty"`
-	References  string `json:"references,omitempty"`
+	CheckName   string     `json:"name"`
+	RiskRating  string     `json:"risk"`
+	Description string     `json:"description"`
+	SkipReason  string     `json:"skipReason,omitempty"`
+	References  references `json:"references,omitempty"`
 
 	// "Support" checks that must have succeeded
 	// For example, checks involving PKCE won't run unless
 	// the PKCE support check succeeds
 	RequiresSupport []string `json:"requiresSupport,omitempty"`
 
+	// Kinds of redirect URI the check applies to: "web", "loopback" or "custom-scheme".
+	// The check is skipped if the redirect_uri in the config file is of another kind,
+	// such as checks for native apps with a web redirect URI
+	RedirectURITypes []string `json:"redirectUriTypes,omitempty"`
+
 	// Output message giving information about why the check failed
 	failMessage string `json:"-"`
 
@@ -54,12 +62,33 @@ type check struct {
 	// Custom defined check function
 	custom *customCheck `json:"-"`
 
+	// Values captured by steps of this check, which
+	// can be referenced by later steps
+	captured map[string]string `json:"-"`
+
 	Steps []step `json:"steps"`
 
 	// State contains result of the check
 	state `json:"-"`
 
 	CheckType checkType `json:"type,omitempty"`
+
+	// Check was run again after the session was lost and re-established
+	Retried bool `json:"retried,omitempty"`
+
+	// Times the check is run again when inconclusive, and the default
+	// for its steps, instead of --retries
+	MaxRetries *int `json:"retries,omitempty"`
+
+	// Seconds the flows of the check's steps wait to be redirected to the
+	// redirect_uri, instead of --timeout. Steps can set their own timeout
+	Timeout int `json:"timeout,omitempty"`
+
+	// Times the check was run, including retries and confirm runs
+	Attempts int `json:"attempts,omitempty"`
+
+	// Confirm runs of a failing check failed as well. Only set if confirm runs were made
+	Consistent *bool `json:"consistent,omitempty"`
 }
 
 type customCheckFunction func(*check, *context.Context) (state, error)
@@ -69,26 +98,67 @@ type customCheck struct {
 	checkContext  customCheckContext
 }
 
-// Init - initializes checks by reading checks from files, identifying
-// custom definitions for checks, setting up support checks
-func Init(ctx context.Context, checkJSONFile string, promptFlag string) {
+// Reauthenticate - called when a check lost the browser session. Returns true
+// if the session had to be re-established, in which case the check is run again
+var Reauthenticate func() bool
+
+// context and prompt flag used to create new flows when a check is run again
+var checksContext context.Context
+var checksPromptFlag string
+
+// options of the checks, provided to Init
+var checksOptions Options
+
+// Options - settings of the checks provided on the command line, passed
+// to Init so that checks do not read command line flags themselves
+type Options struct {
+	// options of the checks' flows. A check's timeout replaces Flow.Timeout
+	Flow oauth.Options
+
+	// address the local listener binds to, and the URL the authorization
+	// server can reach it at. Checks needing the listener are skipped without ListenURL
+	Listen    string
+	ListenURL string
+}
+
+// Init - initializes checks by reading checks from files or the built-in checks, identifying
+// custom definitions for checks, setting up support checks. If rule packs are provided, only
+// their checks are run
+func Init(ctx context.Context, checkJSONFile string, extraCheckFiles, packs []string, promptFlag string, opts Options) {
+	checksContext = ctx
+	checksPromptFlag = promptFlag
+	checksOptions = opts
 	mappings = getMappings()
-	checksList = readChecks(ctx, checkJSONFile, promptFlag)
+	selectedPacks = selectPacks(packs)
+	checksList = readChecks(ctx, checkJSONFile, extraCheckFiles, len(packs) > 0, promptFlag)
 
 	// Remove checks of type "support" and add them to SupportChecksList
 	// TODO: do this during reading checks so we don't have to remove later
-	for i, c := range checksList {
+	var otherChecks []*check
+	for _, c := range checksList {
 		if c.CheckType == support {
-			checksList = append(checksList[:i], checksList[i+1:]...) // remove
 			supportChecksList = append(supportChecksList, c)
+		} else {
+			otherChecks = append(otherChecks, c)
 		}
 	}
+	checksList = otherChecks
 }
 
 // identifies if a check is supported, if so, runs the check
 func (c *check) doCheck() {
 	var state state
 	var err error
+	if !c.redirectURITypeMatches() {
+		c.SkipReason = fmt.Sprintf("Check skipped as it only applies to %s redirect URIs", strings.Join(c.RedirectURITypes, " or "))
+		c.state = skip
+		return
+	}
+	if c.needsListener() && !listenerReachable() {
+		c.SkipReason = listenerUnreachableReason
+		c.state = skip
+		return
+	}
 	if !c.checkSupported() {
 		c.SkipReason = "Check skipped due to missing support for checks defined in requiresSupport"
 		c.state = skip
@@ -106,28 +176,124 @@ func (c *check) doCheck() {
 	}
 }
 
-// DoChecks - completes each support check, followed by other checks
-func DoChecks() {
+// serializes re-establishing the session between checks run in parallel
+var reauthMu sync.Mutex
+
+// number of times the session was re-established
+var reauthCount int
+
+// runs the check, running it again once if the browser session was lost
+// during the check and had to be re-established, and confirming failures
+func (c *check) run() {
+	reauthMu.Lock()
+	count := reauthCount
+	reauthMu.Unlock()
+
+	c.logger().Debug("Running check")
+	c.doCheckWithRetries()
+	if c.sessionLost() && Reauthenticate != nil {
+		c.logger().Warn("Session was lost during the check")
+		if !reauthenticate(count) {
+			return
+		}
+		c.resetFlows()
+		c.doCheckWithRetries()
+		c.Retried = true
+	}
+	c.confirm()
+	c.logger().Debug("Check ended", "state", c.state, "attempts", c.Attempts)
+}
+
+// re-establishes the session, unless another check already did so since
+// the session count was read. Returns true if the check should be run again
+func reauthenticate(count int) bool {
+	reauthMu.Lock()
+	defer reauthMu.Unlock()
+	if reauthCount != count {
+		return true
+	}
+	if !Reauthenticate() {
+		return false
+	}
+	reauthCount++
+	return true
+}
+
+// sessionLost - true if any step of the check lost the browser session.
+// Custom checks manage their own flows, so can not be detected
+func (c *check) sessionLost() bool {
+	for _, s := range c.Steps {
+		if s.FlowInstance != nil && s.FlowInstance.SessionLost() {
+			return true
+		}
+	}
+	return false
+}
+
+// resetFlows - replaces the flow instances and results of each step
+// with new ones, so that the check can be run again
+func (c *check) resetFlows() {
+	c.failMessage = ""
+	c.errorMessage = ""
+	c.captured = nil
+	for i, s := range c.Steps {
+		s.resetFlow()
+		s.attempts = 0
+		c.Steps[i] = s
+	}
+}
+
+// DoChecks - completes each support check, followed by other checks.
+// Other checks are run by up to parallel checks at a time
+func DoChecks(parallel int) {
 	for _, c := range supportChecksList { // Do support checks first to determine support
-		c.doCheck()
+		c.run()
+	}
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	queue := make(chan *check)
+	var wg sync.WaitGroup
+	for n := 0; n < parallel; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range queue {
+				c.run()
+			}
+		}()
 	}
 	for _, c := range checksList { // Do the rest of checks
-		c.doCheck()
+		queue <- c
 	}
+	close(queue)
+	wg.Wait()
 }
 
-// PrintResults - print basic Check results to console
+// PrintResults - print the state of each check on a line, followed by
+// the reason for checks which failed or could not be completed
 func PrintResults() {
 	allChecks := append(supportChecksList, checksList...)
 	for _, c := range allChecks {
-		fmt.Println(c.CheckName, c.state)
-		if c.state == warn {
-			fmt.Println("\t", c.errorMessage)
+		fmt.Printf("%-4s %s\n", c.state, c.CheckName)
+		switch {
+		case c.state == fail && c.failMessage != "":
+			fmt.Println("\t" + c.failMessage)
+		case c.state == warn && c.errorMessage != "":
+			fmt.Println("\t" + c.errorMessage)
 		}
-		fmt.Println("")
+	}
+	for _, p := range packResults() {
+		fmt.Printf("PACK %s %s: %d/%d tested, %d passed, %d failed\n", p.Name, p.Version, p.Tested(), p.Total, p.Pass, p.Fail)
 	}
 }
 
+// PrintSummary - print the machine parseable summary line
+func PrintSummary(s Summary, exitCode int) {
+	fmt.Println(s.Line(exitCode))
+}
+
 // TODO checks:
 // scopes reflected at consent url
 // client secret not required
@@ -143,23 +309,54 @@ func (c *check) runCheck() state {
 	// documentation should be added to say if a check in some cases should be
 	// skipped, we should add a skipMessage in checks.json and a skipfunction
 	// to detect if it should be skipped
+	c.captured = make(map[string]string)
 	for i, step := range c.Steps {
-		state, _ := step.runStep()
+		state := step.runWithRetries(c, i)
+		step.saveEvidence(c.CheckName, c.runNumber(), i)
+		step.storeCaptures(c.captured)
 		step.state = state
 		c.Steps[i] = step
-		if state == pass && step.RequiredOutcome == outcomeSucceed {
-			continue
+
+		// the required outcome can not be met, or not met, by a step which was not run
+		if step.notRun {
+			c.errorMessage = fmt.Sprintf("Step %d was not run: %s", i+1, step.errorMessage)
+			return warn
 		}
-		if state != pass && step.RequiredOutcome == outcomeFail {
-			continue
+
+		// A rejection caused by consent or login does not show the
+		// server rejected what the step tested
+		fi := step.FlowInstance
+		if step.RequiredOutcome == outcomeFail && len(step.ExpectedOutcomes) == 0 && fi.InteractionError() {
+			c.errorMessage = fmt.Sprintf("Step %d was rejected with %s, which may be caused by consent or login rather than the check",
+				i+1, fi.OutcomeErrorCode)
+			return warn
 		}
 
-		// Check failed
-		return fail
+		if !step.satisfied(state) {
+			return fail // Check failed
+		}
 	}
 	return pass
 }
 
+// Checks if the redirect_uri in the config file is of a kind the check applies to
+func (c *check) redirectURITypeMatches() bool {
+	if len(c.RedirectURITypes) == 0 {
+		return true
+	}
+	redirectURI, err := url.Parse(config.OAuthConfig.OAuth2Config.RedirectURL)
+	if err != nil {
+		return false
+	}
+	kind := oauth.RedirectURIKind(redirectURI)
+	for _, t := range c.RedirectURITypes {
+		if t == kind {
+			return true
+		}
+	}
+	return false
+}
+
 // Checks if required support checks passed
 func (c *check) checkSupported() bool {
 	// if this is a support check, return true
@@ -201,6 +398,13 @@ func (c *check) checkSupported() bool {
 		return false
 	}
 
+	// Steps using the secondary client can't run without one
+	for _, s := range c.Steps {
+		if s.Client == clientSecondary && config.OAuthConfig.SecondaryClient == nil {
+			return false
+		}
+	}
+
 	// Check if any flow type is available to support the steps of
 	// this check
 	for i, s := range c.Steps {
@@ -213,6 +417,30 @@ func (c *check) checkSupported() bool {
 			if !sliceContains(supportedFlows, oauth.FlowAuthorizationCode) {
 				return false
 			}
+		case oauth.FlowDevice:
+			if !supportExists("device-flow-supported") {
+				return false
+			}
+		case oauth.FlowResource:
+			if len(s.resourceURLs()) == 0 {
+				return false
+			}
+		case oauth.FlowRevoke:
+			if config.OAuthConfig.RevocationURL == "" {
+				return false
+			}
+		case oauth.FlowIntrospect:
+			if config.OAuthConfig.IntrospectionURL == "" {
+				return false
+			}
+		case oauth.FlowClientCredentials:
+			if !supportExists("client-credentials-supported") {
+				return false
+			}
+		case oauth.FlowPassword:
+			if config.OAuthConfig.ResourceOwnerUsername == "" {
+				return false
+			}
 		default:
 			// This is the case where a flowtype for a step was not set,
 			// so just update it to whatever flowtype is supported
@@ -237,26 +465,62 @@ func supportExists(name string) bool {
 	return false
 }
 
-func readChecks(ctx context.Context, checkFile, promptFlag string) []*check {
-	jsonBytes := config.GenerateChecksInput(checkFile)
+// read checks from the check file, or the built-in checks if no file was provided,
+// then layer checks from the extra check files on top of them
+func readChecks(ctx context.Context, checkFile string, extraFiles []string, onlyPacks bool, promptFlag string) []*check {
+	var ret []*check
+	if checkFile == "" {
+		ret = parseChecks(config.RenderChecksInput(defaultChecks), "built-in checks")
+		ret = layerChecks(ret, parseChecks(config.RenderChecksInput(nativeAppChecks), "built-in native app checks"))
+	} else {
+		ret = parseChecks(config.GenerateChecksInput(checkFile), checkFile)
+	}
+	for _, f := range extraFiles {
+		ret = layerChecks(ret, parseChecks(config.GenerateChecksInput(f), f))
+	}
+	if onlyPacks {
+		ret = filterPackChecks(ret, selectedPacks)
+	}
+	validateWaitConditions(ret)
+
+	return processChecks(ctx, ret, promptFlag)
+}
+
+func parseChecks(jsonBytes []byte, source string) []*check {
 	if len(jsonBytes) <= 0 {
-		log.Fatalf("Error opening or parsing JSON file")
+		log.Fatalf("Error opening or parsing JSON file %s\n", source)
 	}
 
 	var ret []*check
 	err := json.Unmarshal(jsonBytes, &ret)
 	if err != nil {
-		log.Fatalf("Error unmarshalling check JSON file:\n%s\n", err.Error())
+		log.Fatalf("Error unmarshalling check JSON file %s:\n%s\n", source, err.Error())
 	}
-
-	ret, ctx = processChecks(ctx, ret, promptFlag)
-
 	return ret
 }
 
-func processChecks(ctx context.Context, checks []*check, promptFlag string) ([]*check, context.Context) {
+// layerChecks - checks in extra replace the check of the same name in base,
+// and are otherwise added after the checks in base
+func layerChecks(base, extra []*check) []*check {
+	index := make(map[string]int)
+	for i, c := range base {
+		index[c.CheckName] = i
+	}
+	for _, c := range extra {
+		if i, ok := index[c.CheckName]; ok {
+			base[i] = c
+			continue
+		}
+		index[c.CheckName] = len(base)
+		base = append(base, c)
+	}
+	return base
+}
+
+// processChecks - sets up the flows of each check. Every tab is a child of the provided
+// context rather than of another tab, so that a tab can be closed without closing others
+func processChecks(ctx context.Context, checks []*check, promptFlag string) []*check {
 	var ret []*check
-	currCtx := ctx
 	for i, c := range checks {
 		if c.CheckType == "" {
 			c.CheckType = normal
@@ -268,7 +532,7 @@ func processChecks(ctx context.Context, checks []*check, promptFlag string) ([]*
 			if funcMapping == nil {
 				log.Fatal("No function mapping found for check of type CUSTOM")
 			}
-			newCtx, _ := chromedp.NewContext(currCtx)
+			newCtx, _ := chromedp.NewContext(ctx)
 			cust := customCheck{
 				checkFunction: getMapping(c.CheckName),
 				checkContext:  &newCtx,
@@ -288,16 +552,14 @@ func processChecks(ctx context.Context, checks []*check, promptFlag string) ([]*
 					// this will be updated in the Step.runStep() method
 					responseType = ""
 				}
-				// make a new context child for each tabs
-				// update ctx to the current context of the new instance
-				newCtx, newCancel := chromedp.NewContext(currCtx)
-				checks[i].Steps[j].FlowInstance = oauth.NewInstance(newCtx, newCancel, responseType, promptFlag)
-				currCtx = newCtx
+				// make a new tab for each step
+				newCtx, newCancel := chromedp.NewContext(ctx)
+				checks[i].Steps[j].FlowInstance = oauth.NewInstance(newCtx, newCancel, responseType, promptFlag, c.flowOptions())
 			}
 		}
 
 		// append pointer to the check to our list
 		ret = append(ret, checks[i])
 	}
-	return ret, currCtx
+	return ret
 }
@@ -1,5 +1,15 @@
 package checks
 
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+
+	"github.com/morganc3/KOAuth/config"
+	"github.com/morganc3/KOAuth/oauth"
+	"github.com/stretchr/testify/assert"
+)
+
 // func TestAuthUrlFuncs(t *testing.T) {
 // 	authUrl := "https://google.com?client_id=28923189123&state=random_state&redirect_uri=http://example.com&response_type=code"
 // 	urlp, _ := url.Parse(authUrl)
@@ -16,3 +26,64 @@ package checks
 // 	expected := "https://google.com?client_id=28923189123&response_type=code&redirect_uri=https://zzz1.com&redirect_uri=https://zzz2.com"
 // 	assert.Equal(t, expected, urlp.String())
 // }
+
+func TestBuiltInChecks(t *testing.T) {
+	var builtIn []*check
+	err := json.Unmarshal(config.RenderChecksInput(defaultChecks), &builtIn)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, builtIn)
+
+	var nativeApp []*check
+	err = json.Unmarshal(config.RenderChecksInput(nativeAppChecks), &nativeApp)
+	assert.Nil(t, err)
+	for _, c := range nativeApp {
+		assert.NotEmpty(t, c.RedirectURITypes, c.CheckName)
+	}
+}
+
+func TestLayerChecks(t *testing.T) {
+	base := []*check{
+		{CheckName: "a", RiskRating: "low"},
+		{CheckName: "b", RiskRating: "low"},
+	}
+	extra := []*check{
+		{CheckName: "c", RiskRating: "high"},
+		{CheckName: "a", RiskRating: "high"},
+	}
+	layered := layerChecks(base, extra)
+
+	var names, risks []string
+	for _, c := range layered {
+		names = append(names, c.CheckName)
+		risks = append(risks, c.RiskRating)
+	}
+	assert.Equal(t, []string{"a", "b", "c"}, names)
+	assert.Equal(t, []string{"high", "low", "high"}, risks)
+}
+
+func TestPushed(t *testing.T) {
+	authzURL, _ := url.Parse("https://idp.example.com/authorize?client_id=client")
+	s := step{FlowType: oauth.FlowAuthorizationCode, PushOnly: true, FlowInstance: &oauth.FlowInstance{AuthorizationURL: authzURL}}
+	assert.True(t, s.pushed())
+
+	// the request_uri of an earlier push is used instead
+	authzURL.RawQuery += "&request_uri=urn%3Aexample"
+	assert.False(t, s.pushed())
+
+	// a step which only pushes is inconclusive if nothing was pushed
+	st, err := s.pushOnlyState(s.pushed())
+	assert.NotNil(t, err)
+	assert.Equal(t, warn, st)
+	assert.True(t, s.notRun)
+	st, err = s.pushOnlyState(true)
+	assert.Nil(t, err)
+	assert.Equal(t, pass, st)
+}
+
+func TestNeedsListener(t *testing.T) {
+	c := check{Steps: []step{{}, {RequestObject: &requestObject{}}}}
+	assert.False(t, c.needsListener())
+
+	c.Steps[1].RequestObject.ByReference = true
+	assert.True(t, c.needsListener())
+}
@@ -4,12 +4,10 @@ import (
 	"context"
 	"net/url"
 	"strings"
-	"time"
 
 	"github.com/chromedp/cdproto/network"
 	"github.com/chromedp/chromedp"
 	"github.com/morganc3/KOAuth/browser"
-	"github.com/morganc3/KOAuth/config"
 	"github.com/morganc3/KOAuth/oauth"
 )
 
@@ -19,7 +17,7 @@ import (
 
 func clickjackingCheck(c *check, ctx *context.Context) (state, error) {
 	// listen network event
-	authzCodeURL := oauth.GenerateAuthorizationURL(oauth.AuthorizationCodeFlowResponseType, "random-state", config.GetOpt(config.FlagPrompt))
+	authzCodeURL := oauth.GenerateAuthorizationURL(oauth.AuthorizationCodeFlowResponseType, "random-state", checksPromptFlag)
 
 	allHeaders := make(map[string][]string)
 	domain := authzCodeURL.Host
@@ -29,7 +27,8 @@ func clickjackingCheck(c *check, ctx *context.Context) (state, error) {
 		chromedp.Navigate(authzCodeURL.String()),
 		chromedp.WaitVisible(`body`, chromedp.BySearch)}
 
-	browser.RunWithTimeOut(ctx, time.Duration(config.GetOptAsInt(config.FlagTimeout)), actions)
+	_, cancel, _ := browser.RunWithTimeOut(ctx, c.flowTimeout(), actions)
+	cancel()
 
 	if allowsIframes(allHeaders) {
 		return fail, nil
@@ -0,0 +1,307 @@
+package checks
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"github.com/morganc3/KOAuth/browser"
+	"github.com/morganc3/KOAuth/oauth"
+)
+
+// Custom check definitions for the device authorization grant (RFC 8628)
+
+const (
+	userCodeSamples            = 5    // number of user_codes requested to estimate entropy
+	minUserCodeEntropyBits     = 30.0 // RFC 8628 section 6.1 gives 34.5 bits as an example
+	userCodeBruteForceAttempts = 10   // incorrect user_codes entered at the verification page
+	slowDownPolls              = 5    // polls made without respecting the interval
+	maxDeviceExpiryWaitSeconds = 600  // longest expires_in we are willing to wait out
+)
+
+// runDeviceStep - performs a device flow step: requests a device code,
+// browses to the verification page and polls for an access token
+func (s *step) runDeviceStep(authURLParams, exchangeParams map[string][]string) (state, error) {
+	fi := s.FlowInstance
+
+	if !s.SkipAuthorization {
+		deviceParams := fi.DefaultDeviceAuthorizationParams()
+		deleteRequiredExchangeParams(deviceParams, s.DeleteAuthURLParams)
+		addTokenExchangeParams(deviceParams, authURLParams)
+		err := fi.RequestDeviceAuthorization(context.TODO(), deviceParams)
+		if err != nil {
+			s.errorMessage = err.Error()
+			return warn, err
+		}
+	}
+
+	s.TokenExchangeParams = fi.DefaultDeviceTokenParams()
+	deleteRequiredExchangeParams(s.TokenExchangeParams, s.DeleteTokenExchangeParams)
+	addTokenExchangeParams(s.TokenExchangeParams, exchangeParams)
+	if s.SkipAuthorization {
+		return s.exchange()
+	}
+
+	err := fi.DoDeviceVerification()
+	if err != nil {
+		s.errorMessage = err.Error()
+		return warn, err
+	}
+
+	tok, err := fi.PollDeviceToken(context.TODO(), s.TokenExchangeParams)
+	if err != nil {
+		s.errorMessage = err.Error()
+		return warn, err
+	}
+	if len(tok.AccessToken) > 0 {
+		return s.validateToken(tok)
+	}
+	return fail, nil
+}
+
+// requests a device authorization in a new flow instance
+// using the default parameters from the oauth config
+func newDeviceAuthorization(ctx context.Context, opts oauth.Options) (*oauth.FlowInstance, error) {
+	fi := oauth.NewInstance(ctx, nil, "", "DONT_SEND", opts)
+	err := fi.RequestDeviceAuthorization(context.TODO(), fi.DefaultDeviceAuthorizationParams())
+	return fi, err
+}
+
+// Requests several user codes and estimates their entropy from the
+// character classes and length used. Duplicate codes always fail.
+func deviceUserCodeEntropyCheck(c *check, ctx *context.Context) (state, error) {
+	var codes []string
+	for n := 0; n < userCodeSamples; n++ {
+		fi, err := newDeviceAuthorization(*ctx, c.flowOptions())
+		if err != nil {
+			return warn, err
+		}
+		userCode := fi.DeviceAuthorization.UserCode
+		if sliceContains(codes, userCode) {
+			c.failMessage = fmt.Sprintf("user_code %s was issued more than once", userCode)
+			return fail, nil
+		}
+		codes = append(codes, userCode)
+	}
+
+	bits := userCodeEntropy(codes)
+	if bits < minUserCodeEntropyBits {
+		c.failMessage = fmt.Sprintf("Estimated user_code entropy is %.1f bits, less than %.1f bits", bits, minUserCodeEntropyBits)
+		return fail, nil
+	}
+	return pass, nil
+}
+
+// estimates the entropy of user codes in bits, based on the
+// character classes observed and the shortest code length.
+// Separators such as "-" do not contribute to entropy
+func userCodeEntropy(codes []string) float64 {
+	var digits, upper, lower bool
+	length := -1
+	for _, code := range codes {
+		n := 0
+		for _, r := range code {
+			switch {
+			case unicode.IsDigit(r):
+				digits = true
+			case unicode.IsUpper(r):
+				upper = true
+			case unicode.IsLower(r):
+				lower = true
+			default:
+				continue
+			}
+			n++
+		}
+		if length == -1 || n < length {
+			length = n
+		}
+	}
+
+	charset := 0
+	if digits {
+		charset += 10
+	}
+	if upper {
+		charset += 26
+	}
+	if lower {
+		charset += 26
+	}
+	if charset == 0 || length <= 0 {
+		return 0
+	}
+	return float64(length) * math.Log2(float64(charset))
+}
+
+// matches lockout and rate limiting pages, and the URLs of error pages they redirect to
+var rateLimitedPattern = regexp.MustCompile(`(?i)too many (requests|attempts)|rate.?limit|\blocked\b|try again later|` +
+	oauth.SlowDownError + `|` + oauth.AccessDeniedError)
+
+// true if the page loaded in the tab shows attempts are being rate limited
+func rateLimitedPage(ctx context.Context) bool {
+	if u := browser.CurrentURL(ctx); u != nil && rateLimitedPattern.MatchString(u.String()) {
+		return true
+	}
+	return rateLimitedPattern.MatchString(browser.PageText(ctx))
+}
+
+// Enters incorrect user codes at the verification page, expecting the authorization
+// server to rate limit attempts, by responding with 429 or a lockout page, or by
+// refusing to issue a token for the device code with slow_down or access_denied
+func deviceUserCodeBruteForceCheck(c *check, ctx *context.Context) (state, error) {
+	fi, err := newDeviceAuthorization(*ctx, c.flowOptions())
+	if err != nil {
+		return warn, err
+	}
+	da := fi.DeviceAuthorization
+
+	var mu sync.Mutex
+	rateLimited := false
+	chromedp.ListenTarget(*ctx, func(ev interface{}) {
+		resp, ok := ev.(*network.EventResponseReceived)
+		if !ok || resp.Type != network.ResourceTypeDocument {
+			return
+		}
+		if resp.Response.Status == http.StatusTooManyRequests {
+			mu.Lock()
+			rateLimited = true
+			mu.Unlock()
+		}
+	})
+	limited := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return rateLimited
+	}
+
+	for n := 0; n < userCodeBruteForceAttempts && !limited(); n++ {
+		guess := randomUserCodeLike(da.UserCode)
+		actions := []chromedp.Action{network.Enable()}
+		if da.VerificationURIComplete != "" {
+			u, err := url.Parse(da.VerificationURIComplete)
+			if err != nil {
+				return warn, err
+			}
+			oauth.SetQueryParameter(u, oauth.UserCodeParam, guess)
+			actions = append(actions, chromedp.Navigate(u.String()))
+		} else {
+			actions = append(actions,
+				chromedp.Navigate(da.VerificationURI),
+				chromedp.SendKeys(`input:not([type=hidden])`, guess+"\r", chromedp.ByQuery),
+			)
+		}
+		_, cancel, err := browser.RunWithTimeOut(ctx, fi.Options.Timeout, actions)
+		cancel()
+		if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+			return warn, fmt.Errorf("Could not load the verification page: %w", err)
+		}
+		if rateLimitedPage(*ctx) {
+			return pass, nil
+		}
+	}
+	if limited() {
+		return pass, nil
+	}
+
+	// the device code may have been invalidated because of the attempts
+	_, err = fi.Exchange(context.TODO(), fi.DefaultDeviceTokenParams())
+	switch oauth.GetTokenErrorCode(err) {
+	case oauth.SlowDownError, oauth.AccessDeniedError:
+		return pass, nil
+	}
+	c.failMessage = fmt.Sprintf("%d incorrect user_code values were entered without being rate limited", userCodeBruteForceAttempts)
+	return fail, nil
+}
+
+// generates a random user code with the same format as the provided one
+func randomUserCodeLike(code string) string {
+	const (
+		digits = "0123456789"
+		upper  = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+		lower  = "abcdefghijklmnopqrstuvwxyz"
+	)
+	ret := []rune(code)
+	for i, r := range ret {
+		var set string
+		switch {
+		case unicode.IsDigit(r):
+			set = digits
+		case unicode.IsUpper(r):
+			set = upper
+		case unicode.IsLower(r):
+			set = lower
+		default:
+			continue
+		}
+		n, _ := rand.Int(rand.Reader, big.NewInt(int64(len(set))))
+		ret[i] = rune(set[n.Int64()])
+	}
+	return string(ret)
+}
+
+// Polls the token endpoint without waiting for the polling interval,
+// expecting the authorization server to respond with "slow_down"
+func devicePollingSlowDownCheck(c *check, ctx *context.Context) (state, error) {
+	fi, err := newDeviceAuthorization(*ctx, c.flowOptions())
+	if err != nil {
+		return warn, err
+	}
+
+	params := fi.DefaultDeviceTokenParams()
+	for n := 0; n < slowDownPolls; n++ {
+		_, err := fi.Exchange(context.TODO(), params)
+		switch oauth.GetTokenErrorCode(err) {
+		case oauth.SlowDownError:
+			return pass, nil
+		case oauth.AuthorizationPendingError:
+		default:
+			return warn, fmt.Errorf("Unexpected response while polling: %v", err)
+		}
+	}
+	c.failMessage = fmt.Sprintf("Polled %d times without waiting and slow_down was never returned", slowDownPolls)
+	return fail, nil
+}
+
+// Waits until the device code has expired and polls with it, expecting
+// an error other than "authorization_pending"
+func deviceCodeExpiryCheck(c *check, ctx *context.Context) (state, error) {
+	fi, err := newDeviceAuthorization(*ctx, c.flowOptions())
+	if err != nil {
+		return warn, err
+	}
+
+	da := fi.DeviceAuthorization
+	if da.ExpiresIn <= 0 {
+		c.failMessage = "Device authorization response did not contain expires_in"
+		return fail, nil
+	}
+	if da.ExpiresIn > maxDeviceExpiryWaitSeconds {
+		c.SkipReason = fmt.Sprintf("Device code expires in %d seconds, longer than the %d seconds we are willing to wait", da.ExpiresIn, maxDeviceExpiryWaitSeconds)
+		return skip, nil
+	}
+
+	time.Sleep(time.Duration(da.ExpiresIn+da.PollingInterval()) * time.Second)
+	tok, err := fi.Exchange(context.TODO(), fi.DefaultDeviceTokenParams())
+	if err == nil && tok != nil && len(tok.AccessToken) > 0 {
+		c.failMessage = "Expired device code was exchanged for an access token"
+		return fail, nil
+	}
+	switch oauth.GetTokenErrorCode(err) {
+	case oauth.AuthorizationPendingError, oauth.SlowDownError:
+		c.failMessage = "Expired device code was still pending authorization"
+		return fail, nil
+	}
+	return pass, nil
+}
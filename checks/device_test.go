@@ -0,0 +1,36 @@
+package checks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserCodeEntropy(t *testing.T) {
+	// 8 uppercase letters
+	assert.InDelta(t, 37.6, userCodeEntropy([]string{"WDJB-MJHT", "BDWP-HQPK"}), 0.1)
+
+	// 6 digits
+	assert.InDelta(t, 19.9, userCodeEntropy([]string{"123456", "654321"}), 0.1)
+
+	// shortest code determines length
+	assert.InDelta(t, 13.3, userCodeEntropy([]string{"1234", "123456"}), 0.1)
+
+	assert.Equal(t, 0.0, userCodeEntropy([]string{"----"}))
+}
+
+func TestRandomUserCodeLike(t *testing.T) {
+	code := randomUserCodeLike("WDJB-1234")
+	assert.Len(t, code, 9)
+	assert.Equal(t, "-", string(code[4]))
+	assert.Regexp(t, "^[A-Z]{4}-[0-9]{4}$", code)
+}
+
+func TestRateLimitedPattern(t *testing.T) {
+	assert.True(t, rateLimitedPattern.MatchString("Too many attempts, try again later"))
+	assert.True(t, rateLimitedPattern.MatchString("Your account has been locked"))
+	assert.True(t, rateLimitedPattern.MatchString("https://idp.example.com/device?error=access_denied"))
+	assert.False(t, rateLimitedPattern.MatchString("The code you entered is not valid"))
+	assert.False(t, rateLimitedPattern.MatchString("Pop-ups are blocked in this browser"))
+	assert.False(t, rateLimitedPattern.MatchString("https://idp.example.com/unlocked?clocked=1"))
+}
@@ -0,0 +1,192 @@
+package checks
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+)
+
+// Classifications of a check when comparing two scans
+const (
+	diffNew       = "new"       // failed, and did not fail in the baseline
+	diffFixed     = "fixed"     // failed in the baseline, and no longer fails
+	diffChanged   = "changed"   // same check state, but the state of a step changed
+	diffUnchanged = "unchanged" // same check and step states
+	diffRemoved   = "removed"   // only in the baseline
+	diffAdded     = "added"     // only in the new scan, and did not fail
+)
+
+// CheckDiff - comparison of a check between a baseline scan and a new scan
+type CheckDiff struct {
+	CheckName  string   `json:"name"`
+	RiskRating string   `json:"risk,omitempty"`
+	CheckType  string   `json:"type,omitempty"`
+	Status     string   `json:"status"`
+	OldState   string   `json:"oldState,omitempty"`
+	NewState   string   `json:"newState,omitempty"`
+	OldSteps   []string `json:"oldSteps,omitempty"`
+	NewSteps   []string `json:"newSteps,omitempty"`
+}
+
+// DiffReport - comparison of every check between two scans
+type DiffReport struct {
+	Checks []CheckDiff `json:"checks"`
+}
+
+// DiffFiles - compares the JSON output of a baseline scan with a new scan
+func DiffFiles(baselineFile, newFile string) (*DiffReport, error) {
+	baseline, err := readResults(baselineFile)
+	if err != nil {
+		return nil, err
+	}
+	current, err := readResults(newFile)
+	if err != nil {
+		return nil, err
+	}
+	return diffResults(baseline, current), nil
+}
+
+// reads the JSON output of a scan
+func readResults(path string) ([]checkOut, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var results []checkOut
+	err = json.Unmarshal(b, &results)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse scan output %s: %s", path, err)
+	}
+	return results, nil
+}
+
+// compares checks by name, and by the state of each of their steps
+func diffResults(baseline, current []checkOut) *DiffReport {
+	old := make(map[string]checkOut)
+	for _, c := range baseline {
+		old[c.CheckName] = c
+	}
+
+	report := &DiffReport{}
+	seen := make(map[string]bool)
+	for _, c := range current {
+		seen[c.CheckName] = true
+		d := CheckDiff{
+			CheckName:  c.CheckName,
+			RiskRating: c.RiskRating,
+			CheckType:  c.CheckType,
+			NewState:   c.State,
+			NewSteps:   stepStates(c),
+		}
+		o, ok := old[c.CheckName]
+		if ok {
+			d.OldState = o.State
+			d.OldSteps = stepStates(o)
+		}
+
+		switch {
+		case c.State == string(fail) && (!ok || o.State != string(fail)):
+			d.Status = diffNew
+		case !ok:
+			d.Status = diffAdded
+		case o.State == string(fail) && c.State == string(pass):
+			d.Status = diffFixed
+		case o.State == c.State && equalStrings(d.OldSteps, d.NewSteps):
+			d.Status = diffUnchanged
+		default:
+			d.Status = diffChanged
+		}
+		report.Checks = append(report.Checks, d)
+	}
+
+	for _, o := range baseline {
+		if seen[o.CheckName] {
+			continue
+		}
+		report.Checks = append(report.Checks, CheckDiff{
+			CheckName:  o.CheckName,
+			RiskRating: o.RiskRating,
+			CheckType:  o.CheckType,
+			Status:     diffRemoved,
+			OldState:   o.State,
+			OldSteps:   stepStates(o),
+		})
+	}
+	return report
+}
+
+func stepStates(c checkOut) []string {
+	var states []string
+	for _, s := range c.Steps {
+		states = append(states, s.State)
+	}
+	return states
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// whether the check newly fails. Support checks are left out, as they are by Summarize
+func (c CheckDiff) newFailure() bool {
+	return c.Status == diffNew && c.CheckType != string(support)
+}
+
+// NewFailures - number of checks failing in the new scan which did not fail in the baseline
+func (d *DiffReport) NewFailures() int {
+	n := 0
+	for _, c := range d.Checks {
+		if c.newFailure() {
+			n++
+		}
+	}
+	return n
+}
+
+// Print - print checks which are new, fixed or changed, followed by a summary
+func (d *DiffReport) Print() {
+	counts := make(map[string]int)
+	for _, c := range d.Checks {
+		counts[c.Status]++
+		if c.Status == diffUnchanged {
+			continue
+		}
+		fmt.Printf("%-9s %s (%s -> %s)\n", c.Status, c.CheckName, stateOrNone(c.OldState), stateOrNone(c.NewState))
+	}
+
+	var statuses []string
+	for s := range counts {
+		statuses = append(statuses, s)
+	}
+	sort.Strings(statuses)
+	fmt.Println("")
+	for _, s := range statuses {
+		fmt.Printf("%s: %d\n", s, counts[s])
+	}
+}
+
+func stateOrNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
+
+// WriteDiff - write the comparison in JSON format to the output directory
+func (d *DiffReport) WriteDiff(outDir string) error {
+	b, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(removeTrailingSlash(outDir), "diff.json"), b, 0644)
+}
@@ -0,0 +1,56 @@
+package checks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffResults(t *testing.T) {
+	out := func(name, state string, steps ...string) checkOut {
+		c := checkOut{CheckName: name, State: state}
+		for _, s := range steps {
+			c.Steps = append(c.Steps, stepOut{State: s})
+		}
+		return c
+	}
+	baseline := []checkOut{
+		out("still-failing", "FAIL", "PASS"),
+		out("was-failing", "FAIL", "PASS"),
+		out("was-passing", "PASS", "FAIL"),
+		out("step-changed", "PASS", "FAIL", "WARN"),
+		out("removed", "PASS"),
+	}
+	current := []checkOut{
+		out("still-failing", "FAIL", "PASS"),
+		out("was-failing", "PASS", "FAIL"),
+		out("was-passing", "FAIL", "PASS"),
+		out("step-changed", "PASS", "WARN", "FAIL"),
+		out("added-failing", "FAIL"),
+		out("added-passing", "PASS"),
+	}
+
+	d := diffResults(baseline, current)
+	statuses := make(map[string]string)
+	for _, c := range d.Checks {
+		statuses[c.CheckName] = c.Status
+	}
+	assert.Equal(t, map[string]string{
+		"still-failing": diffUnchanged,
+		"was-failing":   diffFixed,
+		"was-passing":   diffNew,
+		"step-changed":  diffChanged,
+		"added-failing": diffNew,
+		"added-passing": diffAdded,
+		"removed":       diffRemoved,
+	}, statuses)
+	assert.Equal(t, 2, d.NewFailures())
+
+	supportCheck := out("pkce-supported", "FAIL")
+	supportCheck.RiskRating = "medium"
+	supportCheck.CheckType = string(support)
+	d = diffResults(nil, []checkOut{supportCheck})
+	assert.Equal(t, diffNew, d.Checks[0].Status)
+	assert.Equal(t, 0, d.NewFailures())
+	assert.Equal(t, 0, d.NewFailuresAtOrAbove("low"))
+}
@@ -0,0 +1,69 @@
+package checks
+
+import (
+	"io/ioutil"
+	"log/slog"
+	"path/filepath"
+	"regexp"
+
+	"github.com/morganc3/KOAuth/browser"
+)
+
+// EvidenceDir - directory the screenshot and page snapshot of each
+// step that used the browser are saved to. If empty, none are saved
+var EvidenceDir string
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// saves a screenshot and snapshot of the page the step's flow ended on, so
+// that the step can be triaged without running it again by hand
+func (s *step) saveEvidence(checkName string, run, index int) {
+	fi := s.FlowInstance
+	if EvidenceDir == "" || fi == nil || !fi.BrowserUsed() {
+		return
+	}
+
+	e, err := browser.CaptureEvidence(fi.Ctx)
+	if err != nil {
+		slog.Warn("Could not capture the page at the end of the step", "check", checkName, "step", index+1, "err", err)
+	}
+	s.finalURL = e.URL
+
+	err = makeDirectory(EvidenceDir)
+	if err != nil {
+		slog.Warn("Could not create evidence directory", "err", err)
+		return
+	}
+	name := stepFileName(checkName, run, index)
+
+	if len(e.Screenshot) > 0 {
+		path := filepath.Join(EvidenceDir, name+".png")
+		if err := ioutil.WriteFile(path, e.Screenshot, 0644); err != nil {
+			slog.Warn("Could not save screenshot", "err", err)
+		} else {
+			s.screenshot = path
+		}
+	}
+
+	// saved as text, so that opening the snapshot does not run the page's scripts
+	if e.DOM != "" {
+		path := filepath.Join(EvidenceDir, name+".html.txt")
+		if err := ioutil.WriteFile(path, []byte(e.DOM), 0644); err != nil {
+			slog.Warn("Could not save page snapshot", "err", err)
+		} else {
+			s.dom = path
+		}
+	}
+}
+
+// path of evidence relative to the output directory, so it can be linked from the report
+func evidencePath(outDir, path string) string {
+	if path == "" {
+		return ""
+	}
+	rel, err := filepath.Rel(outDir, path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(rel)
+}
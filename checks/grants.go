@@ -0,0 +1,58 @@
+package checks
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/morganc3/KOAuth/config"
+	"github.com/morganc3/KOAuth/oauth"
+)
+
+// Requests a token directly from the token endpoint with the
+// client credentials or resource owner password credentials grant
+func (s *step) runGrantStep(exchangeParams map[string][]string) (state, error) {
+	fi := s.FlowInstance
+	switch s.FlowType {
+	case oauth.FlowClientCredentials:
+		s.TokenExchangeParams = fi.DefaultClientCredentialsParams()
+	case oauth.FlowPassword:
+		s.TokenExchangeParams = fi.DefaultPasswordParams()
+	}
+	deleteRequiredExchangeParams(s.TokenExchangeParams, s.DeleteTokenExchangeParams)
+	addTokenExchangeParams(s.TokenExchangeParams, exchangeParams)
+	return s.exchange()
+}
+
+// Custom check definitions for non-browser grants
+
+// Sends a resource owner password credentials grant, using the resource owner
+// from the config file or made up credentials. The grant is disabled if the
+// server responds with unsupported_grant_type or unauthorized_client, and enabled
+// if it issues a token or responds with invalid_grant for bad credentials. Other
+// errors, such as invalid_client, are returned before the grant is processed
+func ropcEnabledCheck(c *check, ctx *context.Context) (state, error) {
+	fi := oauth.NewInstance(*ctx, nil, "", checksPromptFlag, c.flowOptions())
+	v := fi.DefaultPasswordParams()
+	if config.OAuthConfig.ResourceOwnerUsername == "" {
+		v.Set(oauth.UsernameParam, "koauth-"+oauth.RandomString(8))
+		v.Set(oauth.PasswordParam, oauth.RandomString(16))
+	}
+
+	tok, err := fi.Exchange(context.TODO(), v)
+	if err == nil && tok != nil && tok.AccessToken != "" {
+		c.failMessage = "Resource owner password credentials grant issued an access token"
+		return fail, nil
+	}
+
+	switch code := oauth.GetTokenErrorCode(err); code {
+	case oauth.UnsupportedGrantTypeError, oauth.UnauthorizedClientError:
+		return pass, nil
+	case oauth.InvalidGrantError:
+		c.failMessage = fmt.Sprintf("Resource owner password credentials grant is enabled, the server responded with %s", code)
+		return fail, nil
+	case "":
+		return warn, err
+	default:
+		return warn, fmt.Errorf("The server responded with %s, which does not show whether the resource owner password credentials grant is enabled", code)
+	}
+}
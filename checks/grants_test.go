@@ -0,0 +1,38 @@
+package checks
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/morganc3/KOAuth/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestROPCEnabledCheck(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+	defer func(u string) { config.OAuthConfig.OAuth2Config.Endpoint.TokenURL = u }(config.OAuthConfig.OAuth2Config.Endpoint.TokenURL)
+	config.OAuthConfig.OAuth2Config.Endpoint.TokenURL = server.URL
+
+	ctx := context.Background()
+	for code, expected := range map[string]state{
+		"unsupported_grant_type": pass,
+		"unauthorized_client":    pass,
+		"invalid_grant":          fail,
+		// client authentication and request errors are returned before the grant is processed
+		"invalid_client":  warn,
+		"invalid_request": warn,
+		"invalid_scope":   warn,
+	} {
+		body = `{"error":"` + code + `"}`
+		st, _ := ropcEnabledCheck(&check{}, &ctx)
+		assert.Equal(t, expected, st, code)
+	}
+}
@@ -0,0 +1,145 @@
+package checks
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/morganc3/KOAuth/config"
+	"github.com/morganc3/KOAuth/listener"
+	"github.com/morganc3/KOAuth/oauth"
+)
+
+// Request object signing options, as defined in provided JSON check structure
+const (
+	signingConfigured = "configured" // sign with the key from the oauth config
+	signingNone       = "none"       // unsigned request object
+	signingGenerated  = "generated"  // sign with a newly generated key unknown to the server
+)
+
+// time to wait for the authorization server to fetch a request_uri
+const requestURIFetchWait = 3 * time.Second
+
+// local listener shared by checks, started when first needed
+var (
+	listenerOnce   sync.Once
+	sharedListener *listener.Listener
+	listenerErr    error
+)
+
+// reason checks which need the authorization server to reach the local listener are skipped
+const listenerUnreachableReason = "Check skipped as --listen-url was not provided, so the authorization server can not reach the local listener"
+
+// requestObject - JWT-secured authorization request options of a step (RFC 9101)
+type requestObject struct {
+	// How to sign the request object, defaults to "configured"
+	Signing string `json:"signing,omitempty"`
+
+	// Claims added to, or replacing, the claims taken from the Auth URL
+	Claims map[string]interface{} `json:"claims,omitempty"`
+
+	// Serve the request object from the local listener and send
+	// its URL as request_uri instead of sending it by value
+	ByReference bool `json:"byReference,omitempty"`
+}
+
+// signs the parameters of the flow's Auth URL into a request object
+func (ro *requestObject) apply(fi *oauth.FlowInstance) error {
+	var key *oauth.SigningKey
+	var err error
+	switch ro.Signing {
+	case signingNone:
+		key = &oauth.SigningKey{Alg: oauth.AlgNone}
+	case signingGenerated:
+		alg := config.OAuthConfig.RequestObjectAlg
+		if alg == "" || alg == oauth.AlgNone {
+			alg = oauth.AlgRS256
+		}
+		key, err = oauth.GenerateSigningKey(alg)
+	default:
+		key, err = oauth.ConfiguredRequestObjectKey()
+	}
+	if err != nil {
+		return err
+	}
+
+	jwt, err := fi.SignRequestObject(key, ro.Claims)
+	if err != nil {
+		return err
+	}
+	if !ro.ByReference {
+		return nil
+	}
+
+	l, err := getListener()
+	if err != nil {
+		return err
+	}
+	path := "/request/" + oauth.RandomString(16)
+	l.Handle(path, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/oauth-authz-req+jwt")
+		w.Write([]byte(jwt))
+	})
+	oauth.DelQueryParameter(fi.AuthorizationURL, oauth.RequestParam)
+	oauth.SetQueryParameter(fi.AuthorizationURL, oauth.RequestURIParam, l.URL(path))
+	return nil
+}
+
+// gets the shared local listener, starting it if needed
+func getListener() (*listener.Listener, error) {
+	listenerOnce.Do(func() {
+		sharedListener, listenerErr = listener.Start(checksOptions.Listen, checksOptions.ListenURL)
+		if listenerErr == nil {
+			slog.Info("Local listener available", "url", sharedListener.URL("/"))
+		}
+	})
+	return sharedListener, listenerErr
+}
+
+// listenerReachable - true if a URL the authorization server can reach the local listener
+// at was provided. The default address of the listener is only reachable from this host
+func listenerReachable() bool {
+	return checksOptions.ListenURL != ""
+}
+
+// needsListener - true if a step of the check serves its request object from the local listener
+func (c *check) needsListener() bool {
+	for _, s := range c.Steps {
+		if s.RequestObject != nil && s.RequestObject.ByReference {
+			return true
+		}
+	}
+	return false
+}
+
+// Sends a request_uri pointing at the local listener and detects whether
+// the authorization server fetches it. Servers that dereference arbitrary
+// request_uri values can be used to make requests to internal hosts
+func jarRequestURISSRFCheck(c *check, ctx *context.Context) (state, error) {
+	if !listenerReachable() {
+		c.SkipReason = listenerUnreachableReason
+		return skip, nil
+	}
+	l, err := getListener()
+	if err != nil {
+		return warn, err
+	}
+
+	fi := oauth.NewInstance(*ctx, nil, oauth.AuthorizationCodeFlowResponseType, checksPromptFlag, c.flowOptions())
+	path := "/ssrf/" + oauth.RandomString(16)
+	oauth.SetQueryParameter(fi.AuthorizationURL, oauth.RequestURIParam, l.URL(path))
+
+	// the result of the flow doesn't matter, only whether the request_uri was fetched
+	fi.DoAuthorizationRequest()
+	time.Sleep(requestURIFetchWait)
+
+	reqs := l.Requests(path)
+	if len(reqs) == 0 {
+		return pass, nil
+	}
+	c.failMessage = fmt.Sprintf("Authorization server fetched the provided request_uri from %s (User-Agent: %s)", reqs[0].RemoteAddr, reqs[0].UserAgent)
+	return fail, nil
+}
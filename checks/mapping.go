@@ -9,6 +9,18 @@ var mappings map[string]customCheckFunction
 func getMappings() map[string]customCheckFunction {
 	return map[string]customCheckFunction{
 		"clickjacking-in-oauth-handshake": clickjackingCheck,
+		"device-user-code-entropy":        deviceUserCodeEntropyCheck,
+		"device-user-code-brute-force":    deviceUserCodeBruteForceCheck,
+		"device-polling-slow-down":        devicePollingSlowDownCheck,
+		"device-code-expiry":              deviceCodeExpiryCheck,
+		"par-request-uri-expired":         parRequestURIExpiredCheck,
+		"jar-request-uri-ssrf":            jarRequestURISSRFCheck,
+		"issuer-in-error-response":        issuerInErrorResponseCheck,
+		"mix-up-code-redeemable":          mixUpCodeRedeemableCheck,
+		"resource-audience-restriction":   resourceAudienceCheck,
+		"resource-scope-enforcement":      resourceScopeEnforcementCheck,
+		"ropc-grant-enabled":              ropcEnabledCheck,
+		"response-mode-downgrade":         responseModeDowngradeCheck,
 	}
 }
 
@@ -0,0 +1,136 @@
+package checks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/morganc3/KOAuth/config"
+	"github.com/morganc3/KOAuth/oauth"
+	"golang.org/x/oauth2"
+)
+
+// Custom check definitions for authorization server mix-up
+// attacks and issuer identification (RFC 9207)
+
+// Triggers an error response and checks that it contains
+// the "iss" parameter, as error responses can also be mixed up
+func issuerInErrorResponseCheck(c *check, ctx *context.Context) (state, error) {
+	// servers which never return iss are reported by authorization-response-missing-issuer
+	fi := oauth.NewInstance(*ctx, nil, oauth.AuthorizationCodeFlowResponseType, checksPromptFlag, c.flowOptions())
+	err := fi.DoAuthorizationRequest()
+	if err != nil {
+		return warn, err
+	}
+	if fi.ResponseParameter(oauth.IssuerParam) == "" {
+		c.SkipReason = "Check skipped as authorization responses do not contain the iss parameter"
+		return skip, nil
+	}
+
+	fi = oauth.NewInstance(*ctx, nil, oauth.AuthorizationCodeFlowResponseType, checksPromptFlag, c.flowOptions())
+	oauth.SetQueryParameter(fi.AuthorizationURL, oauth.ResponseTypeParam, "koauth_unsupported")
+
+	// an error is expected here, only the redirect matters
+	fi.DoAuthorizationRequest()
+
+	params := fi.AuthorizationResponse
+	if params.Get(oauth.ErrorParam) == "" {
+		return warn, errors.New("Authorization server did not redirect with an error response")
+	}
+
+	iss := params.Get(oauth.IssuerParam)
+	if iss == "" {
+		c.failMessage = "Error response did not contain the iss parameter"
+		return fail, nil
+	}
+	issuer := config.OAuthConfig.Issuer
+	if issuer != "" && iss != issuer {
+		c.failMessage = fmt.Sprintf("iss parameter %s does not match the issuer %s", iss, issuer)
+		return fail, nil
+	}
+	return pass, nil
+}
+
+// Mix-up test harness. A local listener stands in for an attacker authorization
+// server, advertising the honest authorization endpoint alongside its own token
+// endpoint. An authorization code is obtained from the honest authorization
+// server and sent to the attacker's token endpoint, as a client vulnerable to
+// mix-up would. The attacker then attempts to redeem the code at the honest
+// token endpoint without the client's credentials. Only this tool makes requests
+// to the harness, so it does not need --listen-url
+func mixUpCodeRedeemableCheck(c *check, ctx *context.Context) (state, error) {
+	l, err := getListener()
+	if err != nil {
+		return warn, err
+	}
+
+	conf := config.OAuthConfig.OAuth2Config
+	base := "/mixup/" + oauth.RandomString(16)
+	tokenPath := base + "/token"
+	l.Handle(base+"/.well-known/oauth-authorization-server", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 l.URL(base),
+			"authorization_endpoint": conf.Endpoint.AuthURL,
+			"token_endpoint":         l.URL(tokenPath),
+		})
+	})
+	l.Handle(tokenPath, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid_grant"}`))
+	})
+
+	// authorization request to the honest authorization server
+	fi := oauth.NewInstance(*ctx, nil, oauth.AuthorizationCodeFlowResponseType, checksPromptFlag, c.flowOptions())
+	err = fi.DoAuthorizationRequest()
+	if err != nil {
+		return warn, err
+	}
+	code := fi.ResponseParameter(oauth.AuthorizationCodeFlowResponseType)
+	if code == "" {
+		return warn, errors.New("Redirected without Authorization Code")
+	}
+	issPresent := fi.ResponseParameter(oauth.IssuerParam) != ""
+
+	// the mixed up client sends the code to the attacker's token endpoint.
+	// Client credentials are never sent, as they would differ between
+	// authorization servers
+	mixedUp := conf
+	mixedUp.ClientSecret = ""
+	mixedUp.Endpoint.TokenURL = l.URL(tokenPath)
+	mixedUp.Endpoint.AuthStyle = oauth2.AuthStyleInParams
+	exchangeParams := url.Values{
+		oauth.GrantTypeParam:   {oauth.AuthorizationCodeGrantType},
+		oauth.RedirectURIParam: {fi.ProvidedRedirectURL.String()},
+		"code":                 {code},
+	}
+	oauth2.RetrieveToken(context.TODO(), &mixedUp, exchangeParams)
+
+	reqs := l.Requests(tokenPath)
+	if len(reqs) == 0 {
+		return warn, errors.New("Attacker token endpoint did not receive the authorization code")
+	}
+	leaked, err := url.ParseQuery(reqs[len(reqs)-1].Body)
+	if err != nil || leaked.Get("code") == "" {
+		return warn, errors.New("Could not read the authorization code sent to the attacker token endpoint")
+	}
+
+	// the attacker redeems the leaked code at the honest token endpoint
+	attacker := mixedUp
+	attacker.Endpoint.TokenURL = conf.Endpoint.TokenURL
+	fi.Client = &attacker
+	tok, err := fi.Exchange(context.TODO(), leaked)
+	if err != nil || tok == nil || tok.AccessToken == "" {
+		return pass, nil
+	}
+
+	c.failMessage = "An authorization code leaked to an attacker's token endpoint was redeemed without client authentication"
+	if !issPresent {
+		c.failMessage += ". The authorization response did not contain the iss parameter, so clients cannot detect the mix-up"
+	}
+	return fail, nil
+}
@@ -0,0 +1,202 @@
+package checks
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Reference - section of a specification a check is based on
+type Reference struct {
+	Spec    string `json:"spec"`              // such as "RFC 9700"
+	Section string `json:"section,omitempty"` // such as "4.1.3"
+	URL     string `json:"url,omitempty"`
+}
+
+func (r Reference) String() string {
+	if r.Section == "" {
+		return r.Spec
+	}
+	return fmt.Sprintf("%s Section %s", r.Spec, r.Section)
+}
+
+// references - references of a check. Check files written before references were
+// structured contain a single string, which is used as the spec of one reference
+type references []Reference
+
+func (r *references) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*r = nil
+		if s != "" {
+			*r = references{{Spec: s}}
+		}
+		return nil
+	}
+	var list []Reference
+	if err := json.Unmarshal(b, &list); err != nil {
+		return err
+	}
+	*r = list
+	return nil
+}
+
+// Pack - manifest of a rule pack, the checks which test a specification or profile
+type Pack struct {
+	Name        string   `json:"name"`  // used with --packs, such as "oauth2-security-bcp"
+	Title       string   `json:"title"` // such as "OAuth 2.0 Security Best Current Practice (RFC 9700)"
+	Version     string   `json:"version"`
+	Description string   `json:"description"`
+	Checks      []string `json:"checks"`
+}
+
+// packs which are reported on, and which checks are run if --packs was provided
+var selectedPacks []*Pack
+
+// builtInPacks - manifests of the embedded rule packs, sorted by name
+func builtInPacks() []*Pack {
+	entries, err := packFiles.ReadDir("packs")
+	if err != nil {
+		log.Fatal(err)
+	}
+	var ret []*Pack
+	for _, e := range entries {
+		b, err := packFiles.ReadFile(path.Join("packs", e.Name()))
+		if err != nil {
+			log.Fatal(err)
+		}
+		ret = append(ret, parsePack(b, e.Name()))
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Name < ret[j].Name })
+	return ret
+}
+
+func parsePack(b []byte, source string) *Pack {
+	var p Pack
+	err := json.Unmarshal(b, &p)
+	if err != nil {
+		log.Fatalf("Error unmarshalling rule pack %s:\n%s\n", source, err.Error())
+	}
+	if p.Name == "" {
+		log.Fatalf("Rule pack %s has no name\n", source)
+	}
+	return &p
+}
+
+// selectPacks - the built-in packs with the provided names, and packs read from the
+// provided manifest files. All built-in packs are selected if none are provided
+func selectPacks(names []string) []*Pack {
+	builtIn := builtInPacks()
+	if len(names) == 0 {
+		return builtIn
+	}
+
+	var ret []*Pack
+	for _, name := range names {
+		if strings.HasSuffix(name, ".json") {
+			b, err := ioutil.ReadFile(name)
+			if err != nil {
+				log.Fatalf("Could not read rule pack %s: %s\n", name, err)
+			}
+			ret = append(ret, parsePack(b, name))
+			continue
+		}
+		p := findPack(builtIn, name)
+		if p == nil {
+			log.Fatalf("Unknown rule pack %s\n", name)
+		}
+		ret = append(ret, p)
+	}
+	return ret
+}
+
+func findPack(packs []*Pack, name string) *Pack {
+	for _, p := range packs {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// filterPackChecks - support checks, and checks which are part of any of the packs
+func filterPackChecks(checks []*check, packs []*Pack) []*check {
+	var ret []*check
+	for _, c := range checks {
+		if c.CheckType == support || len(packsOf(c.CheckName, packs)) > 0 {
+			ret = append(ret, c)
+		}
+	}
+	return ret
+}
+
+// names of the packs the check is part of
+func packsOf(checkName string, packs []*Pack) []string {
+	var ret []string
+	for _, p := range packs {
+		if sliceContains(p.Checks, checkName) {
+			ret = append(ret, p.Name)
+		}
+	}
+	return ret
+}
+
+// packOut - results of the checks of a pack. Checks of the pack which
+// were not loaded, such as when --checks is provided, are counted as NotRun
+type packOut struct {
+	Name        string `json:"name"`
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description"`
+	Total       int    `json:"total"`
+	Pass        int    `json:"pass"`
+	Fail        int    `json:"fail"`
+	Warn        int    `json:"warn"`
+	Skip        int    `json:"skip"`
+	NotRun      int    `json:"notRun"`
+}
+
+// Tested - checks of the pack which passed or failed
+func (p packOut) Tested() int {
+	return p.Pass + p.Fail
+}
+
+// packResults - coverage of each selected pack by the results of the checks
+func packResults() []packOut {
+	states := make(map[string]state)
+	for _, c := range checksList {
+		states[c.CheckName] = c.state
+	}
+
+	var ret []packOut
+	for _, p := range selectedPacks {
+		out := packOut{
+			Name:        p.Name,
+			Title:       p.Title,
+			Version:     p.Version,
+			Description: p.Description,
+			Total:       len(p.Checks),
+		}
+		for _, name := range p.Checks {
+			s, ok := states[name]
+			switch {
+			case !ok:
+				out.NotRun++
+			case s == pass:
+				out.Pass++
+			case s == fail:
+				out.Fail++
+			case s == warn:
+				out.Warn++
+			default:
+				out.Skip++
+			}
+		}
+		ret = append(ret, out)
+	}
+	return ret
+}
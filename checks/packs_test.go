@@ -0,0 +1,59 @@
+package checks
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/morganc3/KOAuth/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuiltInPacks(t *testing.T) {
+	var builtIn []*check
+	assert.Nil(t, json.Unmarshal(config.RenderChecksInput(defaultChecks), &builtIn))
+	var nativeApp []*check
+	assert.Nil(t, json.Unmarshal(config.RenderChecksInput(nativeAppChecks), &nativeApp))
+	builtIn = layerChecks(builtIn, nativeApp)
+
+	packs := builtInPacks()
+	assert.NotEmpty(t, packs)
+	for _, p := range packs {
+		assert.NotEmpty(t, p.Version, p.Name)
+		for _, name := range p.Checks {
+			var found *check
+			for _, c := range builtIn {
+				if c.CheckName == name {
+					found = c
+				}
+			}
+			if assert.NotNil(t, found, "%s in pack %s", name, p.Name) {
+				assert.NotEqual(t, support, found.CheckType, name)
+				assert.NotEmpty(t, found.References, name)
+			}
+		}
+	}
+
+	assert.Len(t, filterPackChecks(builtIn, []*Pack{{Name: "p", Checks: []string{"ropc-grant-enabled"}}}), len(supportChecksOf(builtIn))+1)
+}
+
+func supportChecksOf(list []*check) []*check {
+	var ret []*check
+	for _, c := range list {
+		if c.CheckType == support {
+			ret = append(ret, c)
+		}
+	}
+	return ret
+}
+
+func TestUnmarshalReferences(t *testing.T) {
+	var c checkOut
+	assert.Nil(t, json.Unmarshal([]byte(`{"references":"RFC 6749"}`), &c))
+	assert.Equal(t, references{{Spec: "RFC 6749"}}, c.References)
+
+	assert.Nil(t, json.Unmarshal([]byte(`{"references":""}`), &c))
+	assert.Nil(t, c.References)
+
+	assert.Nil(t, json.Unmarshal([]byte(`{"references":[{"spec":"RFC 9700","section":"4.1.3"}]}`), &c))
+	assert.Equal(t, "RFC 9700 Section 4.1.3", c.References[0].String())
+}
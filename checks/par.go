@@ -0,0 +1,48 @@
+package checks
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/morganc3/KOAuth/oauth"
+)
+
+// Custom check definitions for pushed authorization requests (RFC 9126)
+
+// longest request_uri lifetime we are willing to wait out
+const maxRequestURIExpiryWaitSeconds = 600
+
+// Pushes an authorization request and waits until the request_uri
+// has expired before browsing to the authorization URL with it
+func parRequestURIExpiredCheck(c *check, ctx *context.Context) (state, error) {
+	fi := oauth.NewInstance(*ctx, nil, oauth.AuthorizationCodeFlowResponseType, checksPromptFlag, c.flowOptions())
+	err := fi.PushAuthorizationRequest(context.TODO())
+	if err != nil {
+		return warn, err
+	}
+
+	pa := fi.PushedAuthorization
+	if pa.ExpiresIn <= 0 {
+		c.failMessage = "Pushed authorization response did not contain expires_in"
+		return fail, nil
+	}
+	if pa.ExpiresIn > maxRequestURIExpiryWaitSeconds {
+		c.SkipReason = fmt.Sprintf("request_uri expires in %d seconds, longer than the %d seconds we are willing to wait", pa.ExpiresIn, maxRequestURIExpiryWaitSeconds)
+		return skip, nil
+	}
+
+	time.Sleep(time.Duration(pa.ExpiresIn+1) * time.Second)
+	// an error is expected here, the outcome shows whether the request_uri was rejected
+	fi.DoAuthorizationRequest()
+	switch {
+	case fi.Outcome == oauth.OutcomeRedirectedWithToken:
+		c.failMessage = "Expired request_uri was accepted and an authorization code was issued"
+		return fail, nil
+	case fi.InteractionError():
+		return warn, fmt.Errorf("Authorization request was rejected with %s, which may be caused by consent or login rather than the expired request_uri", fi.OutcomeErrorCode)
+	case fi.Outcome == oauth.OutcomeRedirectedWithError, fi.Outcome == oauth.OutcomeErrorPage:
+		return pass, nil
+	}
+	return warn, fmt.Errorf("Authorization request ended with outcome %s, which does not show whether the expired request_uri was rejected", fi.OutcomeString())
+}
@@ -9,9 +9,13 @@ import (
 	"path/filepath"
 	"text/template"
 
+	"github.com/morganc3/KOAuth/browser"
 	"github.com/morganc3/KOAuth/oauth"
 )
 
+// Quiet - only the summary line is printed
+var Quiet bool
+
 // structs for output format
 
 type stepOut struct {
@@ -24,36 +28,69 @@ type stepOut struct {
 
 	RequiredOutcome string `json:"requiredOutcome"`
 
+	// Outcome of the flow, such as "redirected-with-error:invalid_request"
+	Outcome string `json:"outcome,omitempty"`
+
+	// Documents loaded during the authorization request, ending with the redirect_uri
+	RedirectChain []browser.Hop `json:"redirectChain,omitempty"`
+
 	FlowType     string              `json:"flowType,omitempty"`
 	FlowInstance *oauth.FlowInstance `json:"flow,omitempty"`
 
+	// Values captured during the step
+	Captured map[string]string `json:"captured,omitempty"`
+
+	// Page the flow ended on, and paths of its screenshot and DOM relative to the output directory
+	FinalURL   string `json:"finalURL,omitempty"`
+	Screenshot string `json:"screenshot,omitempty"`
+	DOM        string `json:"dom,omitempty"`
+
+	// Path of the CDP events of the step's tab relative to the output directory, if saved with --trace
+	Trace string `json:"trace,omitempty"`
+
+	// Times the step was run, including retries
+	Attempts int `json:"attempts,omitempty"`
+
 	// State contains result of the step
 	State string `json:"state"`
 }
 
 type checkOut struct {
-	CheckName    string    `json:"name"`
-	RiskRating   string    `json:"risk"`
-	Description  string    `json:"description"`
-	SkipReason   string    `json:"skipReason,omitempty"`
-	References   string    `json:"references,omitempty"`
-	FailMessage  string    `json:"failMessage,omitempty"`
-	ErrorMessage string    `json:"errorMessage,omitempty"`
-	Steps        []stepOut `json:"steps,omitempty"`
-	State        string    `json:"state"`
+	CheckName    string     `json:"name"`
+	RiskRating   string     `json:"risk"`
+	CheckType    string     `json:"type,omitempty"`
+	Description  string     `json:"description"`
+	SkipReason   string     `json:"skipReason,omitempty"`
+	References   references `json:"references,omitempty"`
+	Packs        []string   `json:"packs,omitempty"`
+	FailMessage  string     `json:"failMessage,omitempty"`
+	ErrorMessage string     `json:"errorMessage,omitempty"`
+	Steps        []stepOut  `json:"steps,omitempty"`
+	State        string     `json:"state"`
+	Retried      bool       `json:"retried,omitempty"`
+	Attempts     int        `json:"attempts,omitempty"`
+	Consistent   *bool      `json:"consistent,omitempty"`
 }
 
 // convert Step to StepOut
-func (s *step) export() stepOut {
+func (s *step) export(outDir string) stepOut {
 	return stepOut{
 		AuthorizationURL: s.FlowInstance.AuthorizationURL.String(),
 		RedirectedToURL:  s.FlowInstance.RedirectedToURL.String(),
 		FailMessage:      s.failMessage,
 		ErrorMessage:     s.errorMessage,
 		RequiredOutcome:  s.RequiredOutcome,
+		Outcome:          s.FlowInstance.OutcomeString(),
+		RedirectChain:    s.FlowInstance.RedirectChain(),
 		State:            string(s.state),
 		FlowType:         s.FlowType,
 		FlowInstance:     s.FlowInstance,
+		Captured:         s.captured,
+		FinalURL:         s.finalURL,
+		Screenshot:       evidencePath(outDir, s.screenshot),
+		DOM:              evidencePath(outDir, s.dom),
+		Trace:            evidencePath(outDir, s.trace),
+		Attempts:         s.attempts,
 	}
 }
 
@@ -89,12 +126,17 @@ func WriteResults(outDir string, htmlReportTemplate string) {
 		// Export steps to format for outputting
 		outCheck.Steps = []stepOut{}
 		for _, s := range steps {
-			outCheck.Steps = append(outCheck.Steps, s.export())
+			outCheck.Steps = append(outCheck.Steps, s.export(outDir))
 		}
 
+		// unexported, so lost when marshalling
+		outCheck.FailMessage = c.failMessage
+		outCheck.ErrorMessage = c.errorMessage
 		outCheck.State = string(c.state)
+		outCheck.Packs = packsOf(c.CheckName, selectedPacks)
 		outList = append(outList, outCheck)
 	}
+	packs := packResults()
 
 	bslice, err := json.Marshal(outList)
 	outFile := filepath.Join(outDir, "output.json")
@@ -104,11 +146,21 @@ func WriteResults(outDir string, htmlReportTemplate string) {
 		log.Fatal(err)
 	}
 
+	bslice, err = json.Marshal(packs)
+	packsFile := filepath.Join(outDir, "packs.json")
+	err = ioutil.WriteFile(packsFile, bslice, 0644)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	htmlReportPath := filepath.Join(outDir, "report.html")
-	renderTemplate(outList, htmlReportTemplate, htmlReportPath)
+	renderTemplate(reportData{Checks: outList, Packs: packs}, htmlReportTemplate, htmlReportPath)
 
-	fmt.Printf("HTML Report has been saved to %s\n", htmlReportPath)
-	fmt.Printf("Raw JSON output has been saved to %s\n", outFile)
+	if !Quiet {
+		fmt.Printf("HTML Report has been saved to %s\n", htmlReportPath)
+		fmt.Printf("Raw JSON output has been saved to %s\n", outFile)
+		fmt.Printf("Rule pack coverage has been saved to %s\n", packsFile)
+	}
 }
 
 // remove trailing slash from output directory if present
@@ -131,26 +183,51 @@ func makeDirectory(outDir string) error {
 	return nil
 }
 
-// render html report template
-func renderTemplate(co []checkOut, htmlReportTemplate, htmlReportPath string) {
-	t := template.New("HTML Report").Delims("[%[", "]%]")
+// read the html report template file, or the embedded template if no file was provided
+func reportTemplate(htmlReportTemplate string) (string, error) {
+	if htmlReportTemplate == "" {
+		return defaultReportTemplate, nil
+	}
+	tpl, err := ioutil.ReadFile(htmlReportTemplate)
+	return string(tpl), err
+}
+
+// reportData - data of the HTML report template. Templates use [%[.]%] for the results of the
+// checks as JSON, which is what String returns, and [%[.PacksJSON]%] for the coverage of rule packs
+type reportData struct {
+	Checks []checkOut
+	Packs  []packOut
+}
+
+func (d reportData) String() string {
+	return marshalReportData(d.Checks)
+}
+
+// PacksJSON - coverage of rule packs as JSON
+func (d reportData) PacksJSON() string {
+	return marshalReportData(d.Packs)
+}
 
-	htmlFile, err := os.Open(htmlReportTemplate)
+func marshalReportData(v interface{}) string {
+	bslice, err := json.Marshal(v)
 	if err != nil {
-		log.Printf("Couldn't open file at %s\n", htmlReportTemplate)
 		log.Fatal(err)
 	}
+	return string(bslice)
+}
 
-	// read report html file
-	tpl, err := ioutil.ReadAll(htmlFile)
+// render html report template
+func renderTemplate(data reportData, htmlReportTemplate, htmlReportPath string) {
+	t := template.New("HTML Report").Delims("[%[", "]%]")
+
+	tpl, err := reportTemplate(htmlReportTemplate)
 	if err != nil {
-		log.Fatal(err)
+		log.Fatalf("Couldn't open file at %s: %s\n", htmlReportTemplate, err)
 	}
 
-	t, err = t.Parse(string(tpl))
+	t, err = t.Parse(tpl)
 	if err != nil {
-		log.Println("Error parsing template")
-		log.Fatal(err)
+		log.Fatalf("Error parsing template: %s\n", err)
 	}
 
 	f, err := os.Create(htmlReportPath)
@@ -158,6 +235,5 @@ func renderTemplate(co []checkOut, htmlReportTemplate, htmlReportPath string) {
 		log.Fatal(err)
 	}
 
-	bslice, err := json.Marshal(co)
-	t.Execute(f, string(bslice))
+	t.Execute(f, data)
 }
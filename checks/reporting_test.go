@@ -0,0 +1,38 @@
+package checks
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderEmbeddedTemplate(t *testing.T) {
+	reportPath := filepath.Join(t.TempDir(), "report.html")
+	renderTemplate(reportData{
+		Checks: []checkOut{{
+			CheckName:   "check",
+			RiskRating:  "high",
+			State:       "FAIL",
+			FailMessage: "</script><script>alert(1)</script>",
+			Packs:       []string{"pack"},
+		}},
+		Packs: []packOut{{Name: "pack", Title: "</script>", Total: 1, Fail: 1}},
+	}, "", reportPath)
+
+	b, err := ioutil.ReadFile(reportPath)
+	assert.Nil(t, err)
+	report := string(b)
+
+	// values from the scan can not end the script the results are embedded in
+	assert.Equal(t, 1, strings.Count(report, "</script>"))
+	assert.Contains(t, report, `"name":"check"`)
+	assert.Contains(t, report, `"name":"pack"`)
+
+	// the report must open without network access
+	assert.NotContains(t, report, "http://")
+	assert.NotContains(t, report, "https://")
+	assert.NotContains(t, report, "src=\"//")
+}
@@ -0,0 +1,225 @@
+package checks
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/chromedp/chromedp"
+	"github.com/morganc3/KOAuth/config"
+	"github.com/morganc3/KOAuth/oauth"
+)
+
+// Steps with the "resource" flow type call protected resources with a
+// token from an earlier step, by default the captured "access_token"
+const defaultResourceToken = "${access_token}"
+
+// audience which no resource should accept tokens for
+const invalidResourceIndicator = "https://koauth.invalid/"
+
+// resourceURLs - resources called by a resource step
+func (s *step) resourceURLs() []string {
+	if s.Resource != nil && s.Resource.URL != "" {
+		return []string{s.Resource.URL}
+	}
+	return config.OAuthConfig.ResourceURLs
+}
+
+// Calls each resource with the token. The step succeeds
+// if any of the resources accepted the token
+func (s *step) runResourceStep(captured map[string]string) (state, error) {
+	var opts oauth.ResourceOptions
+	if s.Resource != nil {
+		opts = *s.Resource
+	}
+	if opts.Token == "" {
+		opts.Token = defaultResourceToken
+	}
+	token, err := substituteCapture(opts.Token, captured)
+	if err != nil {
+		s.errorMessage = err.Error()
+		return warn, err
+	}
+	opts.Token = token
+	if opts.TokenType == "" && s.DPoP != nil {
+		opts.TokenType = oauth.DPoPTokenType
+	}
+
+	accepted, err := requestResources(s.FlowInstance, opts, s.resourceURLs())
+	if err != nil {
+		s.errorMessage = err.Error()
+		return warn, err
+	}
+	if accepted == "" {
+		s.failMessage = "No resource accepted the access token"
+		return fail, nil
+	}
+	return pass, nil
+}
+
+// requestResources - calls each of the resources with the provided options,
+// returning the URL of the first resource which accepted the token
+func requestResources(fi *oauth.FlowInstance, opts oauth.ResourceOptions, urls []string) (string, error) {
+	var lastErr error
+	responded := false
+	for _, u := range urls {
+		opts.URL = u
+		er, err := fi.RequestResource(context.TODO(), opts)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		responded = true
+		if er.ResourceAccepted() {
+			return u, nil
+		}
+	}
+	// only an error if no resource could be reached
+	if !responded {
+		return "", lastErr
+	}
+	return "", nil
+}
+
+// Custom check definitions for protected resources
+
+// Obtains a token for an audience which is not one of the configured resources,
+// using resource indicators (RFC 8707). The authorization server should refuse
+// to issue it, otherwise resources must not accept it. If more than one
+// resource is configured, a token for the first is sent to the others
+func resourceAudienceCheck(c *check, ctx *context.Context) (state, error) {
+	resources := config.OAuthConfig.ResourceURLs
+	if len(resources) == 0 {
+		c.SkipReason = "No resource_url provided in config file"
+		return skip, nil
+	}
+
+	params := url.Values{"resource": {invalidResourceIndicator}}
+	fi, err := resourceTokenFlow(ctx, params, c.flowOptions())
+	if err != nil && !refused(fi) {
+		return warn, err
+	}
+	if err == nil {
+		accepted, err := requestResources(fi, oauth.ResourceOptions{Token: fi.Token.AccessToken}, resources)
+		if err != nil {
+			return warn, err
+		}
+		if accepted != "" {
+			c.failMessage = "Resource " + accepted + " accepted a token issued for the audience " + invalidResourceIndicator
+			return fail, nil
+		}
+	}
+
+	if len(resources) < 2 {
+		return pass, nil
+	}
+	params = url.Values{"resource": {resources[0]}}
+	fi, err = resourceTokenFlow(ctx, params, c.flowOptions())
+	if err != nil {
+		if refused(fi) {
+			// resource indicators are not supported
+			return pass, nil
+		}
+		return warn, err
+	}
+	accepted, err := requestResources(fi, oauth.ResourceOptions{Token: fi.Token.AccessToken}, resources[1:])
+	if err != nil {
+		return warn, err
+	}
+	if accepted != "" {
+		c.failMessage = "Resource " + accepted + " accepted a token issued for the audience " + resources[0]
+		return fail, nil
+	}
+	return pass, nil
+}
+
+// Obtains a token without any of the configured scopes and calls the
+// resources with it. Resources should refuse tokens without the scopes
+// they require
+func resourceScopeEnforcementCheck(c *check, ctx *context.Context) (state, error) {
+	resources := config.OAuthConfig.ResourceURLs
+	if len(resources) == 0 {
+		c.SkipReason = "No resource_url provided in config file"
+		return skip, nil
+	}
+
+	fi, err := resourceTokenFlow(ctx, url.Values{oauth.ScopeParam: {""}}, c.flowOptions())
+	if err != nil {
+		if refused(fi) {
+			// no token was issued without scopes
+			return pass, nil
+		}
+		return warn, err
+	}
+	if scope, ok := fi.Token.Extra(oauth.ScopeParam).(string); ok {
+		configured := config.OAuthConfig.OAuth2Config.Scopes
+		for _, sc := range strings.Fields(scope) {
+			if sliceContains(configured, sc) {
+				return warn, errors.New("Authorization server granted configured scopes which were not requested")
+			}
+		}
+	}
+
+	accepted, err := requestResources(fi, oauth.ResourceOptions{Token: fi.Token.AccessToken}, resources)
+	if err != nil {
+		return warn, err
+	}
+	if accepted != "" {
+		c.failMessage = "Resource " + accepted + " accepted a token without the configured scopes"
+		return fail, nil
+	}
+	return pass, nil
+}
+
+// refused - true if the authorization server rejected the flow's request with an error,
+// such as invalid_target or invalid_scope, rather than the flow timing out or failing
+// to load. Errors caused by consent or login do not show the request was refused
+func refused(fi *oauth.FlowInstance) bool {
+	switch fi.Outcome {
+	case oauth.OutcomeRedirectedWithError:
+		return !fi.InteractionError()
+	case oauth.OutcomeExchangeError:
+		return fi.OutcomeErrorCode != ""
+	}
+	return false
+}
+
+// resourceTokenFlow - obtains an access token through the authorization code flow, with
+// parameters replacing those of both the authorization and token requests. Each flow
+// is run in its own tab, which is closed once the token has been obtained
+func resourceTokenFlow(ctx *context.Context, params url.Values, opts oauth.Options) (*oauth.FlowInstance, error) {
+	tabCtx, cancel := chromedp.NewContext(*ctx)
+	defer cancel()
+	fi := oauth.NewInstance(tabCtx, cancel, oauth.AuthorizationCodeFlowResponseType, checksPromptFlag, opts)
+	for k, v := range params {
+		oauth.SetQueryParameter(fi.AuthorizationURL, k, v[0])
+	}
+	err := fi.DoAuthorizationRequest()
+	if err != nil {
+		return fi, err
+	}
+	code := fi.ResponseParameter(oauth.AuthorizationCodeFlowResponseType)
+	if code == "" {
+		return fi, errors.New("Redirected without Authorization Code")
+	}
+
+	exchangeParams := url.Values{
+		oauth.GrantTypeParam:   {oauth.AuthorizationCodeGrantType},
+		oauth.RedirectURIParam: {fi.ProvidedRedirectURL.String()},
+		"code":                 {code},
+	}
+	for k, v := range params {
+		if k != oauth.ScopeParam {
+			exchangeParams[k] = v
+		}
+	}
+	tok, err := fi.Exchange(context.TODO(), exchangeParams)
+	if err != nil {
+		return fi, err
+	}
+	if tok == nil || tok.AccessToken == "" {
+		return fi, errors.New("No access token was issued")
+	}
+	return fi, nil
+}
@@ -0,0 +1,24 @@
+package checks
+
+import (
+	"testing"
+
+	"github.com/morganc3/KOAuth/oauth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefused(t *testing.T) {
+	fi := &oauth.FlowInstance{Outcome: oauth.OutcomeRedirectedWithError, OutcomeErrorCode: "invalid_target"}
+	assert.True(t, refused(fi))
+
+	fi = &oauth.FlowInstance{Outcome: oauth.OutcomeExchangeError, OutcomeErrorCode: "invalid_scope"}
+	assert.True(t, refused(fi))
+
+	// not rejected by the authorization server because of the request
+	fi = &oauth.FlowInstance{Outcome: oauth.OutcomeRedirectedWithError, OutcomeErrorCode: oauth.LoginRequiredError}
+	assert.False(t, refused(fi))
+	fi = &oauth.FlowInstance{Outcome: oauth.OutcomeTimeout}
+	assert.False(t, refused(fi))
+	fi = &oauth.FlowInstance{Outcome: oauth.OutcomeExchangeError}
+	assert.False(t, refused(fi))
+}
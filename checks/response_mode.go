@@ -0,0 +1,47 @@
+package checks
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/morganc3/KOAuth/config"
+	"github.com/morganc3/KOAuth/oauth"
+)
+
+// Custom check definitions for response modes (OAuth 2.0 Form Post
+// Response Mode and JWT Secured Authorization Response Mode)
+
+// Requests the plain query response mode when the client is registered to use
+// another response mode, such as a JARM response mode. The authorization server
+// should reject the request or keep using the registered response mode
+func responseModeDowngradeCheck(c *check, ctx *context.Context) (state, error) {
+	registered := config.OAuthConfig.ResponseMode
+	if registered == "" || registered == oauth.ResponseModeQuery {
+		c.SkipReason = "No response_mode other than query is provided in the config file"
+		return skip, nil
+	}
+
+	fi := oauth.NewInstance(*ctx, nil, oauth.AuthorizationCodeFlowResponseType, checksPromptFlag, c.flowOptions())
+	oauth.SetQueryParameter(fi.AuthorizationURL, oauth.ResponseModeParam, oauth.ResponseModeQuery)
+
+	// an error is expected here, only the redirect matters
+	fi.DoAuthorizationRequest()
+
+	switch fi.Outcome {
+	case oauth.OutcomeRedirectedWithToken, oauth.OutcomeRedirectedWithError, oauth.OutcomeRedirected, oauth.OutcomeErrorPage:
+	default:
+		return warn, fmt.Errorf("Authorization request ended with outcome %s, which does not show which response mode was used", fi.OutcomeString())
+	}
+	if fi.InteractionError() {
+		return warn, fmt.Errorf("Authorization request was rejected with %s, which may be caused by consent or login rather than the response mode", fi.OutcomeErrorCode)
+	}
+	if fi.ResponseMode != oauth.ResponseModeQuery || fi.ResponseParameter(oauth.AuthorizationCodeFlowResponseType) == "" {
+		return pass, nil
+	}
+	c.failMessage = fmt.Sprintf("An authorization code was returned in the query instead of with the registered response mode %s", registered)
+	if strings.HasSuffix(registered, oauth.ResponseModeJWTSuffix) {
+		c.failMessage += ", so the response was not signed"
+	}
+	return fail, nil
+}
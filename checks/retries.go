@@ -0,0 +1,161 @@
+package checks
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"github.com/morganc3/KOAuth/oauth"
+)
+
+// Retries - times a step is run again after its flow timed out or failed to load
+// without reaching its required outcome, and times an inconclusive check is run
+// again. Checks and steps can override it with "retries"
+var Retries int
+
+// RetryBackoff - delay before the first retry, which doubles with each retry
+var RetryBackoff = 2 * time.Second
+
+// ConfirmRuns - times a failing check is run again to confirm the failure.
+// Checks which do not fail in every run are inconclusive
+var ConfirmRuns int
+
+// longest delay between retries, as a multiple of RetryBackoff
+const maxBackoffShift = 5
+
+// delay before the nth retry
+func retryDelay(n int) time.Duration {
+	shift := n - 1
+	if shift > maxBackoffShift {
+		shift = maxBackoffShift
+	}
+	return RetryBackoff << shift
+}
+
+// number of retries of the check, and the default for its steps
+func (c *check) retries() int {
+	if c.MaxRetries != nil {
+		return *c.MaxRetries
+	}
+	return Retries
+}
+
+// runNumber - number of the run of the check in progress, counting
+// retries, confirm runs and runs after the session was re-established
+func (c *check) runNumber() int {
+	return c.Attempts + 1
+}
+
+// doCheckWithRetries - runs the check, running it again while it is inconclusive
+func (c *check) doCheckWithRetries() {
+	c.doCheck()
+	c.Attempts++
+	for n := 1; n <= c.retries() && c.state == warn; n++ {
+		c.logger().Info("Check was inconclusive, retrying", "retry", n, "retries", c.retries(), "err", c.errorMessage)
+		time.Sleep(retryDelay(n))
+		c.resetFlows()
+		c.doCheck()
+		c.Attempts++
+	}
+}
+
+// confirm - runs a failing check again ConfirmRuns times. If a run does not fail, the
+// failure may have been caused by a slow or flaky flow, so the check is inconclusive
+func (c *check) confirm() {
+	if ConfirmRuns < 1 || c.CheckType == support || c.state != fail {
+		return
+	}
+	failures := 1
+	for n := 0; n < ConfirmRuns; n++ {
+		c.resetFlows()
+		c.doCheckWithRetries()
+		if c.state == fail {
+			failures++
+		}
+	}
+
+	consistent := failures == ConfirmRuns+1
+	c.Consistent = &consistent
+	if !consistent {
+		c.state = warn
+		c.errorMessage = fmt.Sprintf("Check failed in %d of %d runs, so the failure may be caused by a flaky flow",
+			failures, ConfirmRuns+1)
+	}
+}
+
+// runWithRetries - runs the step at the index of the check, running it again while
+// its flow times out or fails to load without reaching the required outcome
+func (s *step) runWithRetries(c *check, index int) state {
+	retries := c.retries()
+	if s.MaxRetries != nil {
+		retries = *s.MaxRetries
+	}
+	logger := c.logger().With("step", index+1)
+	trace := openTrace(c.CheckName, c.runNumber(), index, logger)
+	defer func() { s.trace = trace.close() }()
+
+	for {
+		s.applyFlowOptions(c.flowTimeout())
+		s.FlowInstance.Logger = logger
+		trace.watch(s)
+		state, err := s.runStep(c.captured)
+		s.attempts++
+		// steps which could not be run are not retried, as running them again would not change that
+		var missing *missingCaptureError
+		if errors.As(err, &missing) {
+			s.notRun = true
+		}
+		if s.notRun {
+			logger.Info("Step not run", "err", err)
+			return warn
+		}
+		state = s.outcomeState(state)
+		logger.Debug("Step ended", "state", state, "outcome", s.FlowInstance.OutcomeString(), "err", err)
+		if s.attempts > retries || !s.transientFailure(state) {
+			return state
+		}
+		logger.Info("Step flow did not complete, retrying", "outcome", s.FlowInstance.OutcomeString(), "retry", s.attempts, "retries", retries)
+		time.Sleep(retryDelay(s.attempts))
+		s.resetFlow()
+	}
+}
+
+// satisfied - true if the step's state meets its required outcome
+func (s *step) satisfied(st state) bool {
+	switch s.RequiredOutcome {
+	case outcomeSucceed:
+		return st == pass
+	case outcomeFail:
+		return st != pass
+	case outcomeAny:
+		return true
+	}
+	return false
+}
+
+// transientFailure - the step did not meet its required outcome because the flow
+// timed out or failed to load, which may not happen when it is run again
+func (s *step) transientFailure(st state) bool {
+	fi := s.FlowInstance
+	if fi == nil || s.satisfied(st) {
+		return false
+	}
+	return fi.Outcome == oauth.OutcomeTimeout || fi.Outcome == oauth.OutcomeNavigationError
+}
+
+// resetFlow - replaces the flow instance and results of the step with new ones,
+// closing the tab of the previous flow instance
+func (s *step) resetFlow() {
+	if s.FlowInstance.Cancel != nil {
+		s.FlowInstance.Cancel()
+	}
+	newCtx, newCancel := chromedp.NewContext(checksContext)
+	s.FlowInstance = oauth.NewInstance(newCtx, newCancel, s.FlowInstance.FlowType, checksPromptFlag, s.FlowInstance.Options)
+	s.failMessage = ""
+	s.errorMessage = ""
+	s.captured = nil
+	s.notRun = false
+	s.finalURL, s.screenshot, s.dom, s.trace = "", "", "", ""
+	s.state = ""
+}
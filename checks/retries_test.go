@@ -0,0 +1,82 @@
+package checks
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/morganc3/KOAuth/oauth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryDelay(t *testing.T) {
+	defer func(d time.Duration) { RetryBackoff = d }(RetryBackoff)
+	RetryBackoff = time.Second
+	assert.Equal(t, time.Second, retryDelay(1))
+	assert.Equal(t, 4*time.Second, retryDelay(3))
+	assert.Equal(t, 32*time.Second, retryDelay(10))
+}
+
+func TestTransientFailure(t *testing.T) {
+	s := step{RequiredOutcome: outcomeSucceed, FlowInstance: &oauth.FlowInstance{Outcome: oauth.OutcomeTimeout}}
+	assert.True(t, s.transientFailure(warn))
+
+	// a timeout is the required outcome of steps which must fail
+	s.RequiredOutcome = outcomeFail
+	assert.False(t, s.transientFailure(warn))
+
+	s = step{RequiredOutcome: outcomeSucceed, FlowInstance: &oauth.FlowInstance{Outcome: oauth.OutcomeErrorPage}}
+	assert.False(t, s.transientFailure(fail))
+}
+
+// custom check returning each state in turn
+func sequenceCheck(states ...state) *check {
+	run := 0
+	f := func(*check, *context.Context) (state, error) {
+		st := states[run]
+		run++
+		return st, nil
+	}
+	return &check{CheckName: "sequence", custom: &customCheck{checkFunction: f}}
+}
+
+func TestConfirmAndRetries(t *testing.T) {
+	defer func(l []*check) { supportChecksList = l }(supportChecksList)
+	supportChecksList = []*check{{CheckName: "implicit-flow-supported", CheckType: support, state: pass}}
+	defer func(d time.Duration) { RetryBackoff = d }(RetryBackoff)
+	RetryBackoff = time.Millisecond
+	ConfirmRuns = 2
+	defer func() { ConfirmRuns = 0 }()
+
+	c := sequenceCheck(fail, fail, fail)
+	c.run()
+	assert.Equal(t, fail, c.state)
+	assert.Equal(t, 3, c.Attempts)
+	assert.True(t, *c.Consistent)
+
+	c = sequenceCheck(fail, pass, fail)
+	c.run()
+	assert.Equal(t, warn, c.state)
+	assert.False(t, *c.Consistent)
+
+	// inconclusive runs are retried
+	retries := 1
+	c = sequenceCheck(warn, pass)
+	c.MaxRetries = &retries
+	c.run()
+	assert.Equal(t, pass, c.state)
+	assert.Equal(t, 2, c.Attempts)
+	assert.Nil(t, c.Consistent)
+}
+
+func TestResetFlowClosesTab(t *testing.T) {
+	defer func(ctx context.Context) { checksContext = ctx }(checksContext)
+	checksContext = context.Background()
+
+	tabCtx, cancel := context.WithCancel(context.Background())
+	s := step{FlowInstance: &oauth.FlowInstance{Ctx: tabCtx, Cancel: cancel, FlowType: oauth.AuthorizationCodeFlowResponseType}}
+	s.resetFlow()
+	assert.NotNil(t, tabCtx.Err())
+	assert.NotEqual(t, tabCtx, s.FlowInstance.Ctx)
+	s.FlowInstance.Cancel()
+}
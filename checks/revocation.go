@@ -0,0 +1,78 @@
+package checks
+
+import (
+	"context"
+
+	"github.com/morganc3/KOAuth/oauth"
+)
+
+// Client authentication options for revocation and introspection requests
+const (
+	clientAuthNone = "none" // send no client credentials
+)
+
+// targetToken - token sent to the revocation or introspection endpoint
+type targetToken struct {
+	// Token to send, by default the captured "access_token"
+	Token string `json:"token,omitempty"`
+	Hint  string `json:"hint,omitempty"`
+	// "none" to send the request without client authentication
+	ClientAuth string `json:"clientAuth,omitempty"`
+}
+
+// gets the options for the request, substituting captured values
+func (s *step) targetTokenOptions(captured map[string]string) (oauth.TokenRequestOptions, error) {
+	var t targetToken
+	if s.TargetToken != nil {
+		t = *s.TargetToken
+	}
+	if t.Token == "" {
+		t.Token = defaultResourceToken
+	}
+	token, err := substituteCapture(t.Token, captured)
+	if err != nil {
+		return oauth.TokenRequestOptions{}, err
+	}
+	return oauth.TokenRequestOptions{
+		Token:           token,
+		TokenTypeHint:   t.Hint,
+		Unauthenticated: t.ClientAuth == clientAuthNone,
+	}, nil
+}
+
+// Revokes the token. The step succeeds if the revocation
+// endpoint responded with a 200 status code
+func (s *step) runRevokeStep(captured map[string]string) (state, error) {
+	opts, err := s.targetTokenOptions(captured)
+	if err != nil {
+		s.errorMessage = err.Error()
+		return warn, err
+	}
+	err = s.FlowInstance.RevokeToken(context.TODO(), opts)
+	if err != nil {
+		s.failMessage = err.Error()
+		return fail, nil
+	}
+	return pass, nil
+}
+
+// Introspects the token. The step succeeds if the introspection endpoint
+// responded that the token is active, and fails if it responded that it is
+// not. Any other response does not show whether the token is active
+func (s *step) runIntrospectStep(captured map[string]string) (state, error) {
+	opts, err := s.targetTokenOptions(captured)
+	if err != nil {
+		s.errorMessage = err.Error()
+		return warn, err
+	}
+	introspection, err := s.FlowInstance.IntrospectToken(context.TODO(), opts)
+	if err != nil {
+		s.errorMessage = err.Error()
+		return warn, err
+	}
+	if !oauth.TokenActive(introspection) {
+		s.failMessage = "Introspection response indicated the token is not active"
+		return fail, nil
+	}
+	return pass, nil
+}
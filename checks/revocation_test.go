@@ -0,0 +1,43 @@
+package checks
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/morganc3/KOAuth/config"
+	"github.com/morganc3/KOAuth/oauth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunIntrospectStep(t *testing.T) {
+	status, body := http.StatusOK, `{"active":false}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+	defer func(u string) { config.OAuthConfig.IntrospectionURL = u }(config.OAuthConfig.IntrospectionURL)
+	config.OAuthConfig.IntrospectionURL = server.URL
+
+	captured := map[string]string{"access_token": "tok"}
+	s := step{FlowInstance: &oauth.FlowInstance{}}
+	st, err := s.runIntrospectStep(captured)
+	assert.Nil(t, err)
+	assert.Equal(t, fail, st)
+
+	body = `{"active":true}`
+	st, _ = s.runIntrospectStep(captured)
+	assert.Equal(t, pass, st)
+
+	// errors are not introspection responses, so do not show the token is inactive
+	status, body = http.StatusInternalServerError, `{"error":"server_error"}`
+	st, err = s.runIntrospectStep(captured)
+	assert.NotNil(t, err)
+	assert.Equal(t, warn, st)
+
+	status, body = http.StatusOK, `not json`
+	st, _ = s.runIntrospectStep(captured)
+	assert.Equal(t, warn, st)
+}
@@ -6,22 +6,34 @@ import (
 	"fmt"
 	"log"
 	"net/url"
+	"strings"
 
+	"github.com/morganc3/KOAuth/browser"
+	"github.com/morganc3/KOAuth/config"
 	"github.com/morganc3/KOAuth/oauth"
+	"golang.org/x/oauth2"
 )
 
 const (
 	outcomeFail    = "FAIL"
 	outcomeSucceed = "SUCCEED"
+	outcomeAny     = "ANY" // the outcome of the step does not affect the check
+)
+
+// Clients that a step can use, as defined in provided JSON check structure
+const (
+	clientPrimary   = "primary"
+	clientSecondary = "secondary"
 )
 
 type step struct {
-	// Flow type, authorization-code and implicit are supported
+	// Flow type, authorization-code, implicit and device are supported
 	// if this is empty, it will default to whichever flow is supported
 	// flow, prioritizing implicit
 	FlowType string `json:"flowType,omitempty"`
 
-	// Extra parameters to be added to Auth URL
+	// Extra parameters to be added to Auth URL, or to the
+	// device authorization request for the device flow
 	AuthURLParams map[string][]string `json:"authUrlParams,omitempty"`
 
 	// Default parameters that should be deleted prior to browsing to Auth URL
@@ -45,38 +57,204 @@ type step struct {
 	// Fragment Parameters that must be in URL we are redirected to
 	RedirectMustContainFragment map[string][]string `json:"redirectMustContainFragment,omitempty"`
 
+	// Authorization response must contain the "iss" parameter (RFC 9207)
+	RequireIssuer bool `json:"requireIssuer,omitempty"`
+
+	// How the authorization response must be delivered, such as "query", "fragment",
+	// "form_post", "web_message" or "query.jwt". The step fails if it was delivered in another way
+	ResponseMode string `json:"responseMode,omitempty"`
+
+	// Authorization response must be a JARM response with valid iss, aud
+	// and exp claims, which is not signed with the "none" algorithm
+	ValidateJARM bool `json:"validateJarm,omitempty"`
+
+	// Values to capture from the outcome of this step. Later steps in
+	// the same check can reference them as ${name} in authUrlParams,
+	// tokenExchangeExtraParams and waitForRedirectTo
+	Captures []capture `json:"captures,omitempty"`
+
+	// Push the authorization request parameters to the pushed authorization
+	// request endpoint and browse to the Auth URL with the returned request_uri.
+	// Always done if require_par is set in the oauth config
+	PushAuthorizationRequest bool `json:"pushAuthorizationRequest,omitempty"`
+
+	// Only push the authorization request parameters, without browsing to the Auth URL
+	// or exchanging a code, so that the returned request_uri is left unused for a later step
+	PushOnly bool `json:"pushOnly,omitempty"`
+
+	// Sign the authorization request parameters into a request object
+	RequestObject *requestObject `json:"requestObject,omitempty"`
+
+	// Extra parameters to be added to Auth URL after the authorization
+	// request was pushed or signed into a request object, alongside
+	// the request_uri or request parameter
+	AuthURLParamsAfterPush map[string][]string `json:"authUrlParamsAfterPush,omitempty"`
+
+	// Send DPoP proofs to the token endpoint, optionally
+	// overriding the htu, htm or jti claims of the proofs
+	DPoP *oauth.DPoPOptions `json:"dpop,omitempty"`
+
+	// Fields that must be in the token endpoint response. Values
+	// of "scope" are compared against each space separated scope
+	TokenMustContain map[string][]string `json:"tokenMustContain,omitempty"`
+
+	// Fields that must not be in the token endpoint response. If no
+	// values are provided for a key, the field must not be present
+	TokenMustNotContain map[string][]string `json:"tokenMustNotContain,omitempty"`
+
+	// Request made to protected resources by steps with the "resource"
+	// flow type. If no URL is provided, each configured resource_url is called
+	Resource *oauth.ResourceOptions `json:"resource,omitempty"`
+
+	// Token sent by steps with the "revoke" or "introspect" flow types
+	TargetToken *targetToken `json:"targetToken,omitempty"`
+
+	// Client to use for this step, "primary" (default) or "secondary".
+	// The secondary client must be provided in the oauth config
+	Client string `json:"client,omitempty"`
+
+	// Skip the authorization request and only perform the code exchange.
+	// The code should be provided in tokenExchangeExtraParams, generally
+	// as a value captured by an earlier step
+	SkipAuthorization bool `json:"skipAuthorization,omitempty"`
+
 	failMessage  string `json:"-"`
 	errorMessage string `json:"-"`
 
+	// Values captured during this step
+	captured map[string]string `json:"-"`
+
+	// Page the step's flow ended on, and where its screenshot and DOM were saved
+	finalURL   string `json:"-"`
+	screenshot string `json:"-"`
+	dom        string `json:"-"`
+
+	// Where the CDP events of the step's tab were saved
+	trace string `json:"-"`
+
 	RequiredOutcome string `json:"requiredOutcome"`
 
+	// Outcomes of the flow for which the step succeeds, such as "redirected-with-token"
+	// or "redirected-with-error:invalid_request". Overrides how the step would
+	// otherwise succeed or fail
+	ExpectedOutcomes []string `json:"expectedOutcomes,omitempty"`
+
+	// Seconds the step's flow waits to be redirected to the redirect_uri,
+	// instead of the check's timeout or --timeout
+	Timeout int `json:"timeout,omitempty"`
+
+	// Stop waiting for the redirect once the tab meets these conditions, such as
+	// when a known error page was shown. The outcome is then determined from the page
+	WaitFor *browser.WaitCondition `json:"waitFor,omitempty"`
+
+	// Times the step is run again when its flow times out or fails to load
+	// without reaching the required outcome, instead of the check's retries
+	MaxRetries *int `json:"retries,omitempty"`
+
+	// Times the step was run in the last run of its check
+	attempts int `json:"-"`
+
+	// The step could not be run, such as when it referenced a value which was
+	// not captured, so its check is inconclusive whatever its required outcome
+	notRun bool `json:"-"`
+
 	// State contains result of the step
 	state `json:"state"`
 
 	FlowInstance *oauth.FlowInstance `json:"flow,omitempty"`
 }
 
-func (s *step) runStep() (state, error) {
+// runStep - runs the step, substituting values captured
+// by earlier steps of the same check
+func (s *step) runStep(captured map[string]string) (state, error) {
 	fi := s.FlowInstance
 	authzURL := fi.AuthorizationURL
 
+	authURLParams, err := substituteCaptures(s.AuthURLParams, captured)
+	if err != nil {
+		s.errorMessage = err.Error()
+		return warn, err
+	}
+	exchangeParams, err := substituteCaptures(s.TokenExchangeExtraParams, captured)
+	if err != nil {
+		s.errorMessage = err.Error()
+		return warn, err
+	}
+	waitForRedirectTo, err := substituteCapture(s.WaitForRedirectTo, captured)
+	if err != nil {
+		s.errorMessage = err.Error()
+		return warn, err
+	}
+	afterPushParams, err := substituteCaptures(s.AuthURLParamsAfterPush, captured)
+	if err != nil {
+		s.errorMessage = err.Error()
+		return warn, err
+	}
+
+	if s.DPoP != nil {
+		opts, err := substituteDPoPOptions(*s.DPoP, captured)
+		if err != nil {
+			s.errorMessage = err.Error()
+			return warn, err
+		}
+		err = fi.EnableDPoP(opts)
+		if err != nil {
+			s.errorMessage = err.Error()
+			return warn, err
+		}
+	}
+
+	if s.Client == clientSecondary {
+		if config.OAuthConfig.SecondaryClient == nil {
+			err = errors.New("No secondary_client provided in config file")
+			s.errorMessage = err.Error()
+			return warn, err
+		}
+		fi.UseClient(config.OAuthConfig.SecondaryClient)
+	}
+
 	// first delete any "required" auth URL parameters that we have specfically
 	// defined in the check to be deleted
 	deleteRequiredParams(authzURL, s.DeleteAuthURLParams)
 
 	// now, add additional URL parameters defined in the check
-	addAuthURLParams(authzURL, s.AuthURLParams)
+	addAuthURLParams(authzURL, authURLParams)
 
 	// set the redirect_uri value we will wait to be redirected to
 	// if none was provided, this will default to the value in the redirect_uri URL parameter
-	s.setExpectedRedirectURI()
+	s.setExpectedRedirectURI(waitForRedirectTo)
+
+	push := s.pushed()
+	if s.RequestObject != nil && !s.SkipAuthorization {
+		err = s.RequestObject.apply(fi)
+		if err != nil {
+			s.errorMessage = err.Error()
+			return warn, err
+		}
+	}
+	if push {
+		err = fi.PushAuthorizationRequest(context.TODO())
+		if err != nil {
+			s.errorMessage = err.Error()
+			return warn, err
+		}
+	}
+	if s.PushOnly {
+		return s.pushOnlyState(push)
+	}
+	if push || s.RequestObject != nil {
+		addAuthURLParams(authzURL, afterPushParams)
+	}
 
-	var err error
 	switch s.FlowType {
 	case oauth.FlowAuthorizationCode:
 		s.AddDefaultExchangeParams()
 		deleteRequiredExchangeParams(s.TokenExchangeParams, s.DeleteTokenExchangeParams)
-		addTokenExchangeParams(s.TokenExchangeParams, s.TokenExchangeExtraParams)
+		addTokenExchangeParams(s.TokenExchangeParams, exchangeParams)
+		if s.SkipAuthorization {
+			return s.exchange()
+		}
+
 		err = fi.DoAuthorizationRequest()
 		if err != nil {
 			s.errorMessage = err.Error()
@@ -90,13 +268,11 @@ func (s *step) runStep() (state, error) {
 		}
 
 		redirectedTo := fi.RedirectedToURL
-		ok, err := s.requiredRedirectParamsPresent(redirectedTo)
-		if !ok || err != nil {
-			s.errorMessage = err.Error()
-			return warn, err
+		if st, err := s.validateRedirectParams(redirectedTo); st != pass {
+			return st, err
 		}
 
-		authorizationCode := oauth.GetQueryParameterFirst(redirectedTo, oauth.AuthorizationCodeFlowResponseType)
+		authorizationCode := fi.ResponseParameter(oauth.AuthorizationCodeFlowResponseType)
 		if authorizationCode == "" {
 			s.failMessage = "Redirected without Authorization Code"
 			return fail, nil
@@ -104,18 +280,7 @@ func (s *step) runStep() (state, error) {
 
 		// set authorization code from redirect uri
 		s.TokenExchangeParams[oauth.AuthorizationCodeFlowResponseType] = []string{authorizationCode}
-		// perform exchange
-		tok, err := fi.Exchange(context.TODO(), s.TokenExchangeParams)
-
-		if err != nil {
-			s.errorMessage = err.Error()
-			return warn, err
-		}
-		if err == nil && len(tok.AccessToken) > 0 {
-			return pass, nil
-		}
-
-		return fail, nil
+		return s.exchange()
 
 	case oauth.FlowImplicit:
 		err = fi.DoAuthorizationRequest()
@@ -135,18 +300,31 @@ func (s *step) runStep() (state, error) {
 			return fail, nil
 		}
 
-		if oauth.GetImplicitAccessTokenFromURL(redirectedTo.String()) == "" {
+		if fi.ResponseParameter(oauth.AccessTokenParam) == "" {
 			s.failMessage = "Redirected without Access Token"
 			return fail, nil
 		}
 
-		ok, err := s.requiredRedirectParamsPresent(redirectedTo)
-		if !ok || err != nil {
-			s.errorMessage = err.Error()
-			return warn, err
+		if st, err := s.validateRedirectParams(redirectedTo); st != pass {
+			return st, err
 		}
 
 		return pass, nil
+
+	case oauth.FlowDevice:
+		return s.runDeviceStep(authURLParams, exchangeParams)
+
+	case oauth.FlowResource:
+		return s.runResourceStep(captured)
+
+	case oauth.FlowRevoke:
+		return s.runRevokeStep(captured)
+
+	case oauth.FlowIntrospect:
+		return s.runIntrospectStep(captured)
+
+	case oauth.FlowClientCredentials, oauth.FlowPassword:
+		return s.runGrantStep(exchangeParams)
 	}
 
 	// should never get here
@@ -155,16 +333,140 @@ func (s *step) runStep() (state, error) {
 	return warn, errors.New("Something went wrong")
 }
 
+// applies the step's expected outcomes, if any, to the state of the step
+func (s *step) outcomeState(st state) state {
+	if len(s.ExpectedOutcomes) == 0 {
+		return st
+	}
+	fi := s.FlowInstance
+	for _, expected := range s.ExpectedOutcomes {
+		if fi.OutcomeMatches(expected) {
+			return pass
+		}
+	}
+	s.failMessage = fmt.Sprintf("Outcome %s was not one of the expected outcomes: %s",
+		fi.OutcomeString(), strings.Join(s.ExpectedOutcomes, ", "))
+	return fail
+}
+
+// if the authorization request parameters should be pushed for this step
+func (s *step) pushed() bool {
+	switch s.FlowType {
+	case oauth.FlowDevice, oauth.FlowResource, oauth.FlowRevoke, oauth.FlowIntrospect,
+		oauth.FlowClientCredentials, oauth.FlowPassword:
+		return false
+	}
+	if s.SkipAuthorization {
+		return false
+	}
+	// the step already references pushed parameters
+	if oauth.GetQueryParameterFirst(s.FlowInstance.AuthorizationURL, oauth.RequestURIParam) != "" {
+		return false
+	}
+	return s.PushOnly || s.PushAuthorizationRequest || config.OAuthConfig.RequirePAR
+}
+
+// state of a step which only pushes the parameters, as later steps depend on its request_uri
+func (s *step) pushOnlyState(pushed bool) (state, error) {
+	if !pushed {
+		err := errors.New("Parameters were not pushed, as the Auth URL already contains a request_uri or the step does not use the authorization endpoint")
+		s.errorMessage = err.Error()
+		s.notRun = true
+		return warn, err
+	}
+	return pass, nil
+}
+
+// perform the code exchange with the step's exchange parameters
+func (s *step) exchange() (state, error) {
+	tok, err := s.FlowInstance.Exchange(context.TODO(), s.TokenExchangeParams)
+
+	if err != nil {
+		s.errorMessage = err.Error()
+		return warn, err
+	}
+	if err == nil && len(tok.AccessToken) > 0 {
+		return s.validateToken(tok)
+	}
+
+	return fail, nil
+}
+
+// Checks if the token endpoint response contains the fields defined
+// in the step that it must contain, and none that it must not contain
+func (s *step) validateToken(tok *oauth2.Token) (state, error) {
+	for key, values := range s.TokenMustContain {
+		tokenVals := s.tokenResponseValues(tok, key)
+		for _, v := range values {
+			if !sliceContains(tokenVals, v) {
+				s.failMessage = fmt.Sprintf("Token response is missing value %s for key %s", v, key)
+				return fail, nil
+			}
+		}
+	}
+	for key, values := range s.TokenMustNotContain {
+		tokenVals := s.tokenResponseValues(tok, key)
+		if len(values) == 0 && len(tokenVals) > 0 {
+			s.failMessage = fmt.Sprintf("Token response contains key %s", key)
+			return fail, nil
+		}
+		for _, v := range values {
+			if sliceContains(tokenVals, v) {
+				s.failMessage = fmt.Sprintf("Token response contains value %s for key %s", v, key)
+				return fail, nil
+			}
+		}
+	}
+	return pass, nil
+}
+
+// gets the values of a field of the token endpoint response. If
+// scope was omitted, the requested scope was granted (RFC 6749 5.1)
+func (s *step) tokenResponseValues(tok *oauth2.Token, key string) []string {
+	vals := tokenResponseValues(tok, key)
+	if key != oauth.ScopeParam || len(vals) > 0 {
+		return vals
+	}
+	requested := s.TokenExchangeParams.Get(oauth.ScopeParam)
+	if requested == "" {
+		requested = oauth.GetQueryParameterFirst(s.FlowInstance.AuthorizationURL, oauth.ScopeParam)
+	}
+	return strings.Fields(requested)
+}
+
+func tokenResponseValues(tok *oauth2.Token, key string) []string {
+	var v string
+	switch key {
+	case oauth.AccessTokenParam:
+		v = tok.AccessToken
+	case oauth.RefreshTokenParam:
+		v = tok.RefreshToken
+	case oauth.TokenTypeParam:
+		v = tok.TokenType
+	default:
+		if extra := tok.Extra(key); extra != nil {
+			v = fmt.Sprint(extra)
+		}
+	}
+	if v == "" {
+		return nil
+	}
+	if key == oauth.ScopeParam {
+		return strings.Fields(v)
+	}
+	return []string{v}
+}
+
 // Chrome checks if implicit flow tests pass by if we are redirected
 // to the expected redirect URI without an error. This sets
 // which redirect URI we should be waiting to be redirected to.
-func (s *step) setExpectedRedirectURI() {
-	if len(s.WaitForRedirectTo) > 0 {
+func (s *step) setExpectedRedirectURI(waitForRedirectTo string) {
+	if len(waitForRedirectTo) > 0 {
 		// if we have specifically set the parameter in checks.json
 		// to have a URL we are waiting to be redirected to
 		// this is useful for cases where, for example, we provide
 		// two redirect_uri parameters (one valid and one invalid) as part of a test.
-		maliciousRedirectURI, err := url.Parse(s.WaitForRedirectTo)
+		maliciousRedirectURI, err := url.Parse(waitForRedirectTo)
 		if err != nil {
 			log.Fatalf("Bad WaitForRedirectTo value\n")
 		}
@@ -228,11 +530,14 @@ func deleteRequiredExchangeParams(v url.Values, p []string) {
 	}
 }
 
-// Checks if the URL we were redirected to contains the
-// parameters defined in the step that it must contain
-// Checks RedirectMustContainFragment for implicit flow and
-// RedirectMustContainURL for authorization code flow
-func (s *step) requiredRedirectParamsPresent(redirectedTo *url.URL) (bool, error) {
+// Validates the parameters of the URL we were redirected to. Checks if it
+// contains the parameters defined in the step that it must contain:
+// RedirectMustContainFragment for implicit flow and
+// RedirectMustContainURL for authorization code flow.
+// The response must be delivered with the step's response mode, if any.
+// If an issuer is provided in the oauth config, the "iss" parameter
+// (RFC 9207) must match it, and must be present if the step requires it
+func (s *step) validateRedirectParams(redirectedTo *url.URL) (state, error) {
 	var getParamFunc func(*url.URL, string) []string
 	var requiredParams map[string][]string
 	switch s.FlowType {
@@ -245,18 +550,48 @@ func (s *step) requiredRedirectParamsPresent(redirectedTo *url.URL) (bool, error
 		getParamFunc = oauth.GetFragmentParameterAll
 		requiredParams = s.RedirectMustContainFragment
 	default:
-		return false, errors.New("Bad flow type")
+		return warn, errors.New("Bad flow type")
 	}
 
 	for key, values := range requiredParams {
 		redirectURLVals := getParamFunc(redirectedTo, key)
 		for _, v := range values {
 			if !sliceContains(redirectURLVals, v) {
-				return false, fmt.Errorf("Missing value %s for key %s", v, key)
+				err := fmt.Errorf("Missing value %s for key %s", v, key)
+				s.errorMessage = err.Error()
+				return warn, err
 			}
 		}
 	}
-	return true, nil
+
+	fi := s.FlowInstance
+	if s.ResponseMode != "" && fi.ResponseMode != s.ResponseMode {
+		s.failMessage = fmt.Sprintf("Authorization response was delivered with response mode %s, not %s", fi.ResponseMode, s.ResponseMode)
+		return fail, nil
+	}
+	if s.ValidateJARM {
+		err := fi.ValidateJARM(config.OAuthConfig.Issuer, fi.ClientConfig().ClientID)
+		if err != nil {
+			s.failMessage = err.Error()
+			return fail, nil
+		}
+	}
+
+	// the iss parameter is part of the response, which may not be in the URL
+	iss := fi.AuthorizationResponse[oauth.IssuerParam]
+	if len(iss) == 0 {
+		if s.RequireIssuer {
+			s.failMessage = "Authorization response did not contain the iss parameter"
+			return fail, nil
+		}
+		return pass, nil
+	}
+	issuer := config.OAuthConfig.Issuer
+	if issuer != "" && iss[0] != issuer {
+		s.failMessage = fmt.Sprintf("iss parameter %s does not match the issuer %s", iss[0], issuer)
+		return fail, nil
+	}
+	return pass, nil
 }
 
 // checks if slice of strings contains given string
@@ -0,0 +1,96 @@
+package checks
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Risk ratings of checks, from lowest to highest
+var riskRatings = []string{"info", "low", "medium", "high"}
+
+// FailOnNone - fail threshold for which no failing check causes a non-zero exit code
+const FailOnNone = "none"
+
+// ValidFailThreshold - true if the threshold is a risk rating or "none"
+func ValidFailThreshold(threshold string) bool {
+	return threshold == FailOnNone || riskIndex(threshold) >= 0
+}
+
+func riskIndex(risk string) int {
+	for i, r := range riskRatings {
+		if strings.EqualFold(r, risk) {
+			return i
+		}
+	}
+	return -1
+}
+
+// riskAtLeast - true if the risk rating is at or above the threshold
+func riskAtLeast(risk, threshold string) bool {
+	if threshold == FailOnNone {
+		return false
+	}
+	return riskIndex(risk) >= riskIndex(threshold)
+}
+
+// Summary - counts of check states, and failing checks by risk rating.
+// Support checks are not counted, as failing only means something is unsupported
+type Summary struct {
+	Total      int
+	Pass       int
+	Fail       int
+	Warn       int
+	Skip       int
+	FailByRisk map[string]int
+}
+
+// Summarize - summarize the results of every check that was run
+func Summarize() Summary {
+	s := Summary{FailByRisk: make(map[string]int)}
+	for _, c := range checksList {
+		s.Total++
+		switch c.state {
+		case pass:
+			s.Pass++
+		case fail:
+			s.Fail++
+			s.FailByRisk[strings.ToLower(c.RiskRating)]++
+		case warn:
+			s.Warn++
+		case skip:
+			s.Skip++
+		}
+	}
+	return s
+}
+
+// FailuresAtOrAbove - number of failing checks with a risk rating at or above the threshold
+func (s Summary) FailuresAtOrAbove(threshold string) int {
+	n := 0
+	for risk, count := range s.FailByRisk {
+		if riskAtLeast(risk, threshold) {
+			n += count
+		}
+	}
+	return n
+}
+
+// Line - single line, machine parseable summary of the results
+func (s Summary) Line(exitCode int) string {
+	line := fmt.Sprintf("KOAUTH_SUMMARY total=%d pass=%d fail=%d warn=%d skip=%d", s.Total, s.Pass, s.Fail, s.Warn, s.Skip)
+	for i := len(riskRatings) - 1; i >= 0; i-- {
+		line += fmt.Sprintf(" fail_%s=%d", riskRatings[i], s.FailByRisk[riskRatings[i]])
+	}
+	return line + fmt.Sprintf(" exit=%d", exitCode)
+}
+
+// NewFailuresAtOrAbove - number of new failures with a risk rating at or above the threshold
+func (d *DiffReport) NewFailuresAtOrAbove(threshold string) int {
+	n := 0
+	for _, c := range d.Checks {
+		if c.newFailure() && riskAtLeast(c.RiskRating, threshold) {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,20 @@
+package checks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummary(t *testing.T) {
+	s := Summary{Total: 4, Pass: 1, Fail: 3, FailByRisk: map[string]int{"high": 1, "low": 1, "info": 1}}
+	assert.Equal(t, 3, s.FailuresAtOrAbove("info"))
+	assert.Equal(t, 2, s.FailuresAtOrAbove("low"))
+	assert.Equal(t, 1, s.FailuresAtOrAbove("medium"))
+	assert.Equal(t, 0, s.FailuresAtOrAbove(FailOnNone))
+	assert.Equal(t, "KOAUTH_SUMMARY total=4 pass=1 fail=3 warn=0 skip=0 fail_high=1 fail_medium=0 fail_low=1 fail_info=1 exit=2", s.Line(2))
+
+	assert.True(t, ValidFailThreshold("medium"))
+	assert.True(t, ValidFailThreshold("none"))
+	assert.False(t, ValidFailThreshold("critical"))
+}
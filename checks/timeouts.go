@@ -0,0 +1,47 @@
+package checks
+
+import (
+	"log"
+	"time"
+
+	"github.com/morganc3/KOAuth/oauth"
+)
+
+// flowTimeout - how long flows of the check wait to be redirected to the redirect_uri
+func (c *check) flowTimeout() time.Duration {
+	if c.Timeout > 0 {
+		return time.Duration(c.Timeout) * time.Second
+	}
+	return checksOptions.Flow.Timeout
+}
+
+// flowOptions - options of the check's flows, with the check's timeout
+func (c *check) flowOptions() oauth.Options {
+	opts := checksOptions.Flow
+	opts.Timeout = c.flowTimeout()
+	return opts
+}
+
+// applyFlowOptions - sets the timeout and wait conditions of the step's flow.
+// The step's timeout takes precedence over the timeout of its check
+func (s *step) applyFlowOptions(checkTimeout time.Duration) {
+	s.FlowInstance.Options.Timeout = checkTimeout
+	if s.Timeout > 0 {
+		s.FlowInstance.Options.Timeout = time.Duration(s.Timeout) * time.Second
+	}
+	s.FlowInstance.WaitFor = s.WaitFor
+}
+
+// exits if a step waits for a URL pattern which is not a valid regular expression
+func validateWaitConditions(checks []*check) {
+	for _, c := range checks {
+		for i, s := range c.Steps {
+			if s.WaitFor == nil {
+				continue
+			}
+			if err := s.WaitFor.Validate(); err != nil {
+				log.Fatalf("Invalid waitFor url of step %d of check %s: %s\n", i+1, c.CheckName, err)
+			}
+		}
+	}
+}
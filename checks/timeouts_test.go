@@ -0,0 +1,34 @@
+package checks
+
+import (
+	"testing"
+	"time"
+
+	"github.com/morganc3/KOAuth/browser"
+	"github.com/morganc3/KOAuth/oauth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlowTimeouts(t *testing.T) {
+	checksOptions.Flow = oauth.Options{Timeout: 4 * time.Second, Native: true}
+	defer func() { checksOptions = Options{} }()
+
+	c := &check{}
+	assert.Equal(t, 4*time.Second, c.flowTimeout())
+	c.Timeout = 30
+	assert.Equal(t, 30*time.Second, c.flowTimeout())
+
+	// flows of custom checks get the check's timeout along with the other options
+	assert.Equal(t, oauth.Options{Timeout: 30 * time.Second, Native: true}, c.flowOptions())
+
+	wait := &browser.WaitCondition{Selector: "#error"}
+	s := step{FlowInstance: &oauth.FlowInstance{}, WaitFor: wait}
+	s.applyFlowOptions(c.flowTimeout())
+	assert.Equal(t, 30*time.Second, s.FlowInstance.Options.Timeout)
+	assert.Equal(t, wait, s.FlowInstance.WaitFor)
+
+	// the step's timeout takes precedence
+	s.Timeout = 90
+	s.applyFlowOptions(c.flowTimeout())
+	assert.Equal(t, 90*time.Second, s.FlowInstance.Options.Timeout)
+}
@@ -0,0 +1,76 @@
+package checks
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/morganc3/KOAuth/browser"
+)
+
+// TraceDir - directory the CDP events of each step's tab are saved to,
+// as a JSON object on each line. If empty, no traces are saved
+var TraceDir string
+
+// logger - logger of the check's messages
+func (c *check) logger() *slog.Logger {
+	return slog.With("check", c.CheckName)
+}
+
+// name of the evidence and trace files of a step in a run of its check. Runs are
+// numbered, so that retries and confirm runs do not replace the files of earlier runs
+func stepFileName(checkName string, run, index int) string {
+	return fmt.Sprintf("%s-run%d-step%d", unsafeFileChars.ReplaceAllString(checkName, "_"), run, index+1)
+}
+
+// stepTrace - trace of the tabs of each run of a step. A nil
+// stepTrace, such as when TraceDir is empty, does nothing
+type stepTrace struct {
+	file  *os.File
+	trace *browser.Trace
+}
+
+// openTrace - creates the trace file of the step in the given run of its check.
+// Events of a step which is retried within the run are added to the same file
+func openTrace(checkName string, run, index int, logger *slog.Logger) *stepTrace {
+	if TraceDir == "" {
+		return nil
+	}
+	err := makeDirectory(TraceDir)
+	if err != nil {
+		logger.Warn("Could not create trace directory", "err", err)
+		return nil
+	}
+	f, err := os.Create(filepath.Join(TraceDir, stepFileName(checkName, run, index)+".jsonl"))
+	if err != nil {
+		logger.Warn("Could not create trace file", "err", err)
+		return nil
+	}
+	return &stepTrace{file: f, trace: browser.NewTrace(f)}
+}
+
+// watch - adds the events of the step's current tab to the trace
+func (t *stepTrace) watch(s *step) {
+	if t == nil || s.FlowInstance == nil {
+		return
+	}
+	t.trace.Watch(s.FlowInstance.Ctx)
+}
+
+// close - stops the trace and closes its file, returning its path. Steps which
+// did not use the browser have no events, so their file is removed
+func (t *stepTrace) close() string {
+	if t == nil {
+		return ""
+	}
+	t.trace.Stop()
+	path := t.file.Name()
+	info, err := t.file.Stat()
+	t.file.Close()
+	if err == nil && info.Size() == 0 {
+		os.Remove(path)
+		return ""
+	}
+	return path
+}
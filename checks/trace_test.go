@@ -0,0 +1,42 @@
+package checks
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStepTrace(t *testing.T) {
+	assert.Equal(t, "redirect_uri_path_append-run3-step2", stepFileName("redirect_uri path append", 3, 1))
+
+	TraceDir = ""
+	assert.Nil(t, openTrace("pkce-downgrade", 1, 0, slog.Default()))
+
+	TraceDir = filepath.Join(t.TempDir(), "trace")
+	defer func() { TraceDir = "" }()
+
+	// steps which did not use the browser have no events, so no trace is kept
+	tr := openTrace("pkce-downgrade", 1, 0, slog.Default())
+	assert.NotNil(t, tr)
+	path := filepath.Join(TraceDir, "pkce-downgrade-run1-step1.jsonl")
+	assert.Equal(t, "", tr.close())
+	_, err := os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+
+	tr = openTrace("pkce-downgrade", 1, 0, slog.Default())
+	tr.file.WriteString("{}\n")
+	assert.Equal(t, path, tr.close())
+	_, err = os.Stat(path)
+	assert.Nil(t, err)
+
+	// a later run of the check does not replace the trace of an earlier one
+	tr = openTrace("pkce-downgrade", 2, 0, slog.Default())
+	tr.file.WriteString("{}\n")
+	assert.Equal(t, filepath.Join(TraceDir, "pkce-downgrade-run2-step1.jsonl"), tr.close())
+	b, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "{}\n", string(b))
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/morganc3/KOAuth/checks"
+)
+
+// diff - compares the JSON output of two scans: "KOAuth diff old.json new.json".
+// Returns the exit code, which is exitFailures only if there are new failures
+func diff(args []string) int {
+	if len(args) != 2 {
+		fmt.Fprintln(os.Stderr, "Usage: KOAuth diff <baseline output.json> <new output.json>")
+		return exitError
+	}
+	d, err := checks.DiffFiles(args[0], args[1])
+	if err != nil {
+		log.Println(err)
+		return exitError
+	}
+	d.Print()
+	if d.NewFailures() > 0 {
+		return exitFailures
+	}
+	return exitOK
+}
+
+// compares the scan just written to the output directory with a baseline scan,
+// returning the number of new failures at or above the fail threshold
+func compareBaseline(baseline, outDir, failOn string, quiet bool) int {
+	d, err := checks.DiffFiles(baseline, filepath.Join(outDir, "output.json"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if !quiet {
+		fmt.Println("")
+		fmt.Println("Comparison with baseline", baseline)
+		d.Print()
+	}
+	err = d.WriteDiff(outDir)
+	if err != nil {
+		slog.Warn("Could not save comparison with baseline", "err", err)
+	}
+	return d.NewFailuresAtOrAbove(failOn)
+}
@@ -2,40 +2,123 @@ package cmd
 
 import (
 	"context"
+	"log"
+	"log/slog"
 	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/morganc3/KOAuth/browser"
 	"github.com/morganc3/KOAuth/checks"
 	"github.com/morganc3/KOAuth/config"
+	"github.com/morganc3/KOAuth/oauth"
 )
 
+// Exit codes. log.Fatal exits with exitError
+const (
+	exitOK       = 0 // no failing checks at or above the fail threshold
+	exitError    = 1 // the scan could not be completed
+	exitFailures = 2 // checks at or above the fail threshold failed
+)
+
+// options of the flows used to authenticate and validate the session,
+// which are also passed to the checks
+var flowOptions oauth.Options
+
 // Execute - Parse CLI flags, OAuth configuration file,
 // initialize browser session, and begin performing checks
 func Execute() {
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		os.Exit(diff(os.Args[2:]))
+	}
+
 	config.CliFlags.InitCliFlags() // Initialize and Parse CLI Flags
 
-	cancel := browser.InitChromeSession() // Initialize Chrome browser configuration
-	defer cancel()
+	// log messages of every package, including the log package, are written by this logger
+	logger, err := config.NewLogger(os.Stderr, config.GetOpt(config.FlagLogLevel), config.GetOpt(config.FlagLogFormat))
+	if err != nil {
+		log.Fatal(err)
+	}
+	slog.SetDefault(logger)
+
+	failOn := config.GetOpt(config.FlagFailOn)
+	if !checks.ValidFailThreshold(failOn) {
+		log.Fatalf("Invalid --%s value %s\n", config.FlagFailOn, failOn)
+	}
+	quiet := config.GetOptAsBool(config.FlagQuiet)
+
+	// Initialize Chrome browser configuration
+	cancel := browser.InitChromeSession(config.GetOpt(config.FlagUserAgent), config.GetOpt(config.FlagProxy))
+	flowOptions = oauth.Options{
+		Timeout:   time.Duration(config.GetOptAsInt(config.FlagTimeout)) * time.Second,
+		Native:    config.GetOptAsBool(config.FlagNative),
+		Proxy:     config.GetOpt(config.FlagProxy),
+		UserAgent: config.GetOpt(config.FlagUserAgent),
+	}
 
 	config.OAuthConfig.Init() // Parse OAuth configuration file provided
 
 	// first tab's context and CancelFunc
 	// this will be the first window, which
 	// sets up authentication to the authorization server
-	fctx, fctxCancel := initSession(config.GetOpt(config.FlagAuthenticationURL))
-	defer fctxCancel()
+	authURL := config.GetOpt(config.FlagAuthenticationURL)
+	sessionFile := config.GetOpt(config.FlagSessionFile)
+	fctx, fctxCancel := initSession(authURL, sessionFile)
+
+	// if the session is lost mid-scan, pause to authenticate again
+	checks.Reauthenticate = func() bool {
+		return reauthenticate(fctx, authURL, sessionFile)
+	}
 
 	checkFile := config.GetOpt(config.FlagChecks)
+	extraCheckFiles := config.GetOptAsList(config.FlagExtraChecks)
+	packs := config.GetOptAsList(config.FlagPacks)
+	parallel := config.GetOptAsInt(config.FlagParallel)
 	promptFlag := config.GetOpt(config.FlagPrompt)
 	outDir := config.GetOpt(config.FlagOut)
 	reportTemplate := config.GetOpt(config.FlagReportTemplate)
-	performChecks(fctx, checkFile, promptFlag, outDir, reportTemplate)
+	checks.Retries = config.GetOptAsInt(config.FlagRetries)
+	checks.RetryBackoff = time.Duration(config.GetOptAsInt(config.FlagRetryBackoff)) * time.Second
+	checks.ConfirmRuns = config.GetOptAsInt(config.FlagConfirm)
+	trace := config.GetOptAsBool(config.FlagTrace)
+	performChecks(fctx, checkFile, extraCheckFiles, packs, promptFlag, outDir, reportTemplate, parallel, quiet, trace)
+
+	// with a baseline, only new failures affect the exit code
+	summary := checks.Summarize()
+	failures := summary.FailuresAtOrAbove(failOn)
+	baseline := config.GetOpt(config.FlagBaseline)
+	if baseline != "" {
+		failures = compareBaseline(baseline, outDir, failOn, quiet)
+	}
+
+	code := exitOK
+	if failures > 0 {
+		code = exitFailures
+	}
+	checks.PrintSummary(summary, code)
+
+	// deferred functions do not run on os.Exit
+	fctxCancel()
+	cancel()
+	os.Exit(code)
 }
 
-func performChecks(ctx context.Context, checkFile, promptFlag, outDir, htmlReportTemplate string) {
-	checks.Init(ctx, checkFile, promptFlag)
-	checks.DoChecks()
-	checks.PrintResults()
+func performChecks(ctx context.Context, checkFile string, extraCheckFiles, packs []string, promptFlag, outDir, htmlReportTemplate string,
+	parallel int, quiet, trace bool) {
+	checks.Quiet = quiet
+	checks.EvidenceDir = filepath.Join(outDir, "evidence")
+	if trace {
+		checks.TraceDir = filepath.Join(outDir, "trace")
+	}
+	checks.Init(ctx, checkFile, extraCheckFiles, packs, promptFlag, checks.Options{
+		Flow:      flowOptions,
+		Listen:    config.GetOpt(config.FlagListen),
+		ListenURL: config.GetOpt(config.FlagListenURL),
+	})
+	checks.DoChecks(parallel)
+	if !quiet {
+		checks.PrintResults()
+	}
 	checks.WriteResults(outDir, htmlReportTemplate)
 }
 
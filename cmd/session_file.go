@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+
+	"github.com/chromedp/chromedp"
+	"github.com/morganc3/KOAuth/browser"
+	"github.com/morganc3/KOAuth/config"
+	"github.com/morganc3/KOAuth/oauth"
+)
+
+// Restores the session saved in the session file, returning
+// true if the restored session is still authenticated
+func restoreSession(ctx context.Context, sessionFile string, key []byte) bool {
+	state, err := browser.LoadSessionFile(sessionFile, key)
+	if err != nil {
+		slog.Warn("Could not load session file", "err", err)
+		return false
+	}
+	err = browser.RestoreSession(ctx, state)
+	if err != nil {
+		slog.Warn("Could not restore session", "err", err)
+		return false
+	}
+	return sessionValid(ctx)
+}
+
+// A session is valid if an authorization code is issued without any interaction,
+// using prompt=none. The flow is run in a new tab which is closed afterwards, so that
+// the redirect it ends with is not seen by later flows in the tab of the context
+func sessionValid(ctx context.Context) bool {
+	tabCtx, cancel := chromedp.NewContext(ctx)
+	defer cancel()
+
+	i := oauth.NewInstance(tabCtx, cancel, oauth.AuthorizationCodeFlowResponseType, "none", flowOptions)
+	if config.OAuthConfig.RequirePAR {
+		err := i.PushAuthorizationRequest(tabCtx)
+		if err != nil {
+			slog.Info("Could not validate session", "err", err)
+			return false
+		}
+	}
+	err := i.DoAuthorizationRequest()
+	if err != nil {
+		slog.Info("Session is no longer valid", "err", err)
+		return false
+	}
+	if i.ResponseParameter(oauth.AuthorizationCodeFlowResponseType) == "" {
+		slog.Info("Session is no longer valid, no authorization code was issued")
+		return false
+	}
+	return true
+}
+
+// Saves the authenticated session to the session file. Local storage is
+// saved for the origins of the Auth URL and the authentication URL
+func saveSession(ctx context.Context, sessionFile, authURL string, key []byte) {
+	var origins []string
+	for _, u := range []string{config.OAuthConfig.OAuth2Config.Endpoint.AuthURL, authURL} {
+		if origin := urlOrigin(u); origin != "" && !containsString(origins, origin) {
+			origins = append(origins, origin)
+		}
+	}
+
+	state, err := browser.CaptureSession(ctx, origins)
+	if err != nil {
+		slog.Warn("Could not save session", "err", err)
+		return
+	}
+	err = browser.SaveSessionFile(sessionFile, state, key)
+	if err != nil {
+		slog.Warn("Could not save session", "err", err)
+		return
+	}
+	slog.Info("Saved authenticated session", "file", sessionFile)
+}
+
+func urlOrigin(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil || parsed.Host == "" {
+		return ""
+	}
+	return parsed.Scheme + "://" + parsed.Host
+}
+
+func containsString(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
@@ -5,10 +5,12 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"log/slog"
 	"os"
 
 	"github.com/chromedp/chromedp"
 	"github.com/morganc3/KOAuth/browser"
+	"github.com/morganc3/KOAuth/config"
 	"github.com/morganc3/KOAuth/oauth"
 )
 
@@ -17,14 +19,61 @@ import (
 // or the context times out. The purpose of this initialization is to
 // setup cookies, localstorage, indexdb, etc. in the browser.
 
-func initSession(authURL string) (context.Context, context.CancelFunc) {
+// If a session file was provided, the session is restored from it when still
+// valid, otherwise the session is saved to it after authenticating.
+func initSession(authURL, sessionFile string) (context.Context, context.CancelFunc) {
 	ctx, cancel := chromedp.NewContext(browser.ChromeExecContext)
+	if sessionFile == "" {
+		authenticate(ctx, cancel, authURL)
+		return ctx, cancel
+	}
+
+	// get the key before authenticating, so that a missing key is not
+	// only noticed once the user has authenticated
+	key, err := browser.SessionKey()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if fileExists(sessionFile) && restoreSession(ctx, sessionFile, key) {
+		slog.Info("Restored authenticated session", "file", sessionFile)
+		return ctx, cancel
+	}
+
+	authenticate(ctx, cancel, authURL)
+	saveSession(ctx, sessionFile, authURL, key)
+	return ctx, cancel
+}
+
+// Re-establishes a session lost during the scan, using the same mechanism as
+// the initial authentication. Returns false if the session turned out to
+// still be valid, such as when a flow only ended on a page resembling a login page.
+// Authentication happens in a new tab which is closed afterwards, as the first tab
+// still has the listeners of the initial authentication
+func reauthenticate(ctx context.Context, authURL, sessionFile string) bool {
+	if sessionValid(ctx) {
+		return false
+	}
+	fmt.Println("The session at the authorization server was lost, you must authenticate again.")
+	tabCtx, cancel := chromedp.NewContext(ctx)
+	authenticate(tabCtx, cancel, authURL)
+	cancel()
 
+	if sessionFile != "" {
+		key, err := browser.SessionKey()
+		if err != nil {
+			log.Fatal(err)
+		}
+		saveSession(ctx, sessionFile, authURL, key)
+	}
+	return true
+}
+
+func authenticate(ctx context.Context, cancel context.CancelFunc, authURL string) {
 	// if an authUrl was provided, auth there and return. Otherwise we
 	// will do an oauth flow which should prompt the user to authenticate
 	if authURL != "" {
 		waitForAuth(ctx, authURL)
-		return ctx, cancel
+		return
 	}
 
 	// TODO: some servers might not support implicit flow
@@ -35,12 +84,18 @@ func initSession(authURL string) (context.Context, context.CancelFunc) {
 	// when they have authenticated
 
 	// We should be prompted for auth as this is our first request
-	i := oauth.NewInstance(ctx, cancel, oauth.ImplicitFlowResponseType, "DONT_SEND")
+	i := oauth.NewInstance(ctx, cancel, oauth.ImplicitFlowResponseType, "DONT_SEND", flowOptions)
+	if config.OAuthConfig.RequirePAR {
+		err := i.PushAuthorizationRequest(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
 
 	urlString := i.AuthorizationURL.String()
 
 	// adds listener listening for a redirect to our redirect_uri
-	ch := browser.WaitRedirect(ctx, i.ProvidedRedirectURL.Host, i.ProvidedRedirectURL.Path)
+	ch := browser.WaitRedirect(ctx, oauth.NewRedirectMatcher(i.ProvidedRedirectURL))
 
 	err := chromedp.Run(ctx, chromedp.Navigate(urlString))
 	if err != nil {
@@ -50,15 +105,14 @@ func initSession(authURL string) (context.Context, context.CancelFunc) {
 	select {
 	case <-ctx.Done():
 		log.Fatal("Context was cancelled")
-	case urlstr := <-ch:
-		i.RedirectedToURL = urlstr
+	case redirect := <-ch:
+		i.RedirectedToURL = redirect.URL
 		err = i.GetURLError() // get error as defined in rfc6749
 		if err != nil {
 			log.Fatal(err)
 		}
-		log.Println("Successfully authenticated")
+		slog.Info("Successfully authenticated")
 	}
-	return ctx, cancel
 }
 
 // Waits for the user to authenticate in the browser
@@ -4,6 +4,7 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 
 	flag "github.com/ogier/pflag"
 )
@@ -13,6 +14,7 @@ type cliFlag struct {
 	hint         string
 	defaultValue string
 	value        *string
+	isBool       bool // can be passed without a value, such as --quiet
 }
 
 type cliFlagsMap map[string]*cliFlag
@@ -24,6 +26,8 @@ var CliFlags cliFlagsMap
 const (
 	FlagConfig            = "config"
 	FlagChecks            = "checks"
+	FlagExtraChecks       = "extra-checks"
+	FlagPacks             = "packs"
 	FlagOut               = "out"
 	FlagAuthenticationURL = "authentication-url"
 	FlagProxy             = "proxy"
@@ -32,6 +36,20 @@ const (
 	FlagPrompt            = "prompt"
 	FlagClientAuth        = "client-auth"
 	FlagReportTemplate    = "report-template"
+	FlagListen            = "listen"
+	FlagListenURL         = "listen-url"
+	FlagSessionFile       = "session-file"
+	FlagBaseline          = "baseline"
+	FlagFailOn            = "fail-on"
+	FlagQuiet             = "quiet"
+	FlagNative            = "native"
+	FlagParallel          = "parallel"
+	FlagRetries           = "retries"
+	FlagRetryBackoff      = "retry-backoff"
+	FlagConfirm           = "confirm"
+	FlagLogLevel          = "log-level"
+	FlagLogFormat         = "log-format"
+	FlagTrace             = "trace"
 )
 
 // InitCliFlags - Initialize CliFlagsMap and parse CLI flags
@@ -39,7 +57,12 @@ func (c *cliFlagsMap) InitCliFlags() {
 	*c = make(cliFlagsMap)
 
 	c.newFlag(FlagConfig, "input oauth configuration file", "config.json")
-	c.newFlag(FlagChecks, "file containing checks to run", "./checks/rules/checks.json")
+	c.newFlag(FlagChecks, "file containing checks to run, instead of the built-in checks", "")
+	c.newFlag(FlagExtraChecks, `Comma separated files containing checks to run in addition to the checks 
+		from --checks or the built-in checks. Checks with the same name as an earlier check replace it.`, "")
+	c.newFlag(FlagPacks, `Comma separated names of built-in rule packs, such as "oauth2-security-bcp", or rule pack 
+		manifest files ending in .json. Only the checks of these packs are run, and the report shows the 
+		coverage of these packs instead of every built-in pack.`, "")
 	c.newFlag(FlagOut, "directory for output to be stored", "output/")
 	c.newFlag(FlagAuthenticationURL,
 		`Url to originally authenticate at to establish an authenticated session in the browser. 
@@ -47,7 +70,8 @@ func (c *cliFlagsMap) InitCliFlags() {
 		"")
 	c.newFlag(FlagProxy, "HTTP Proxy <ip>:<port>", "")
 	c.newFlag(FlagUserAgent, "User-Agent Header for Chrome", `Chrome`)
-	c.newFlag(FlagTimeout, "Timeout for waiting for OAuth redirects to redirect_uri", "4")
+	c.newFlag(FlagTimeout, `Seconds to wait for OAuth redirects to redirect_uri. Checks and steps can 
+		set "timeout" to override it.`, "4")
 	c.newFlag(FlagPrompt, `Value of "prompt" parameter in authorization request. If the authorization 
 		server does not support prompt=none, it should be set to "login" or "select_account". If the 
 		pressence of the prompt parameter breaks the flow, set to this flag to the string "DONT_SEND" 
@@ -55,7 +79,36 @@ func (c *cliFlagsMap) InitCliFlags() {
 	c.newFlag(FlagClientAuth, `Client Authentication Method: "BASIC", "BODY", or "auto", to indicate if 
 		client ID and client secret should be sent in an HTTP Basic authentication header or in the POST body, 
 		or should be auto detected.`, "auto")
-	c.newFlag(FlagReportTemplate, "HTML report template to consume JSON output", "./checks/assets/report.html")
+	c.newFlag(FlagReportTemplate, "HTML report template to consume JSON output, instead of the built-in report", "")
+	c.newFlag(FlagListen, `Address for the local listener used by checks that detect requests made by 
+		the authorization server, or that serve content to it`, "127.0.0.1:0")
+	c.newFlag(FlagListenURL, `URL the authorization server can reach the local listener at. If left blank, 
+		the address of the listener is used`, "")
+
+	c.newFlag(FlagSessionFile, `Encrypted file to save the authenticated browser session to. If it exists and 
+		the session is still valid, it is restored instead of authenticating. The base64 encoded 256 bit 
+		key must be provided in the KOAUTH_SESSION_KEY environment variable.`, "")
+	c.newFlag(FlagBaseline, `JSON output of an earlier scan to compare results with. Checks are marked as new 
+		findings, fixed or unchanged, and the exit code is 2 only if there are new failures.`, "")
+	c.newFlag(FlagFailOn, `Lowest risk rating of failing checks which cause a non-zero exit code: "info", "low", 
+		"medium" or "high". Set to "none" to always exit with 0 when the scan completes.`, "low")
+	c.newBoolFlag(FlagQuiet, "Only print a summary line of the results")
+	c.newBoolFlag(FlagNative, `Perform authorization requests with an HTTP client using the browser's cookies, 
+		following redirects to the redirect_uri. The browser is only used for pages which are not redirects 
+		or error pages, such as consent pages or pages which need JavaScript.`)
+	c.newFlag(FlagParallel, "Number of checks to run at the same time, after support checks have been run", "1")
+	c.newFlag(FlagRetries, `Number of times a step is run again when its flow times out or fails to load without 
+		reaching its required outcome, and an inconclusive check is run again. Checks and steps can set 
+		"retries" to override it.`, "0")
+	c.newFlag(FlagRetryBackoff, "Seconds to wait before the first retry, doubling with each retry", "2")
+	c.newFlag(FlagConfirm, `Number of times a failing check is run again to confirm the failure. Checks which 
+		do not fail in every run are reported as inconclusive.`, "0")
+	c.newFlag(FlagLogLevel, `Lowest level of log messages which are printed: "debug", "info", "warn" or "error". 
+		At "debug", each check and step, and the navigations of each flow are logged.`, "info")
+	c.newFlag(FlagLogFormat, `Format of log messages: "text" for key=value pairs, or "json" for a JSON 
+		object on each line`, LogFormatText)
+	c.newBoolFlag(FlagTrace, `Save the Chrome DevTools Protocol events of each step's tab to the trace 
+		directory of --out, as a JSON object on each line`)
 
 	c.parseCliFlags() // parse CLI flags
 	filePathsExist()  // ensure file paths provided by CLI flags exist
@@ -70,6 +123,11 @@ func (c cliFlagsMap) newFlag(name, hint string, defaultValue string) {
 	c[name] = &f
 }
 
+func (c cliFlagsMap) newBoolFlag(name, hint string) {
+	c.newFlag(name, hint, "false")
+	c[name].isBool = true
+}
+
 // parse cli flags, storing in CliFlagsMap
 func (c cliFlagsMap) parseCliFlags() {
 	for _, v := range c {
@@ -80,10 +138,42 @@ func (c cliFlagsMap) parseCliFlags() {
 }
 
 func (c cliFlagsMap) parseFlag(cf *cliFlag) {
+	if cf.isBool {
+		val := cf.defaultValue
+		flag.Var(boolString{&val}, cf.name, cf.hint)
+		c[cf.name].value = &val
+		return
+	}
 	val := flag.String(cf.name, cf.defaultValue, cf.hint)
 	c[cf.name].value = val
 }
 
+// boolString - boolean flag value stored as a string, so that
+// boolean flags can be read like any other option
+type boolString struct {
+	value *string
+}
+
+func (b boolString) String() string {
+	if b.value == nil {
+		return ""
+	}
+	return *b.value
+}
+
+func (b boolString) Set(s string) error {
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	*b.value = strconv.FormatBool(v)
+	return nil
+}
+
+func (b boolString) IsBoolFlag() bool {
+	return true
+}
+
 // GetOpt - get cli option value
 func GetOpt(name string) string {
 	return *CliFlags[name].value
@@ -98,13 +188,45 @@ func GetOptAsInt(name string) int {
 	return v
 }
 
+// GetOptAsBool - get cli option as bool
+func GetOptAsBool(name string) bool {
+	v, err := strconv.ParseBool(*CliFlags[name].value)
+	if err != nil {
+		log.Fatalf("Bad option value - could not be converted to bool\n")
+	}
+	return v
+}
+
+// GetOptAsList - get comma separated cli option values, empty values are removed
+func GetOptAsList(name string) []string {
+	var ret []string
+	for _, v := range strings.Split(*CliFlags[name].value, ",") {
+		v = strings.TrimSpace(v)
+		if v != "" {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
+
 // Must only be called after flags have been parsed
 func filePathsExist() {
-	// ensure input check JSON file exists
-	checkFile := GetOpt(FlagChecks)
-	if !fileExists(checkFile) {
-		log.Printf("Check file at %s does not exist\n", checkFile)
-		log.Fatal("The default check file is in the repository at KOAuth/checks/rules/checks.json")
+	// ensure input check JSON files exist, if provided
+	checkFiles := GetOptAsList(FlagExtraChecks)
+	if checkFile := GetOpt(FlagChecks); checkFile != "" {
+		checkFiles = append(checkFiles, checkFile)
+	}
+	for _, checkFile := range checkFiles {
+		if !fileExists(checkFile) {
+			log.Fatalf("Check file at %s does not exist\n", checkFile)
+		}
+	}
+
+	// ensure rule pack manifest files exist, if provided
+	for _, pack := range GetOptAsList(FlagPacks) {
+		if strings.HasSuffix(pack, ".json") && !fileExists(pack) {
+			log.Fatalf("Rule pack manifest at %s does not exist\n", pack)
+		}
 	}
 
 	// ensure OAuth config file exists
@@ -113,11 +235,16 @@ func filePathsExist() {
 		log.Fatalf("OAuth configuration file at %s does not exist\n", oauthConfig)
 	}
 
-	// ensure HTML report template file exists
+	// ensure baseline scan output exists, if provided
+	baseline := GetOpt(FlagBaseline)
+	if baseline != "" && !fileExists(baseline) {
+		log.Fatalf("Baseline file at %s does not exist\n", baseline)
+	}
+
+	// ensure HTML report template file exists, if provided
 	reportTemplate := GetOpt(FlagReportTemplate)
-	if !fileExists(reportTemplate) {
-		log.Printf("HTML Report template file at %s does not exist\n", reportTemplate)
-		log.Fatal("The default report template file is in the repository at KOAuth/checks/assets/report.html")
+	if reportTemplate != "" && !fileExists(reportTemplate) {
+		log.Fatalf("HTML Report template file at %s does not exist\n", reportTemplate)
 	}
 }
 
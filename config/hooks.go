@@ -0,0 +1,61 @@
+package config
+
+import (
+	"log"
+	"regexp"
+)
+
+// Browser automation hook actions, as defined in the oauth config file
+const (
+	HookActionClick    = "click"    // click the element matching the selector
+	HookActionSendKeys = "sendKeys" // type the value into the element matching the selector
+	HookActionWait     = "wait"     // wait until the element matching the selector is visible
+	HookActionSleep    = "sleep"    // sleep for the provided number of seconds
+)
+
+// Hook - browser actions run when a page matching the URL pattern is loaded
+// during a flow, such as clicking "Allow" on a consent page or picking an
+// account on an account chooser page
+type Hook struct {
+	URLPattern string       `json:"url_pattern"`
+	Actions    []HookAction `json:"actions"`
+
+	pattern *regexp.Regexp
+}
+
+// HookAction - a single browser action of a hook
+type HookAction struct {
+	Action   string `json:"action"`
+	Selector string `json:"selector,omitempty"`
+	Value    string `json:"value,omitempty"`
+	Seconds  int    `json:"seconds,omitempty"`
+}
+
+// Matches - true if the URL matches the hook's URL pattern
+func (h *Hook) Matches(u string) bool {
+	return h.pattern != nil && h.pattern.MatchString(u)
+}
+
+// validate the hooks from the oauth config file, compiling their URL patterns
+func compileHooks(hooks []Hook) []Hook {
+	for i, h := range hooks {
+		pattern, err := regexp.Compile(h.URLPattern)
+		if err != nil {
+			log.Fatalf("Invalid url_pattern %s in hook: %s\n", h.URLPattern, err)
+		}
+		hooks[i].pattern = pattern
+
+		for _, a := range h.Actions {
+			switch a.Action {
+			case HookActionClick, HookActionSendKeys, HookActionWait:
+				if a.Selector == "" {
+					log.Fatalf("Hook action %s requires a selector\n", a.Action)
+				}
+			case HookActionSleep:
+			default:
+				log.Fatalf("Unsupported hook action %s\n", a.Action)
+			}
+		}
+	}
+	return hooks
+}
@@ -0,0 +1,35 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewLogger(t *testing.T) {
+	var b bytes.Buffer
+	logger, err := NewLogger(&b, "warn", LogFormatJSON)
+	assert.Nil(t, err)
+
+	logger.Info("not written")
+	logger.Warn("step timed out", "check", "pkce-downgrade", "step", 1)
+
+	var record map[string]interface{}
+	assert.Nil(t, json.Unmarshal(b.Bytes(), &record))
+	assert.Equal(t, "WARN", record["level"])
+	assert.Equal(t, "step timed out", record["msg"])
+	assert.Equal(t, "pkce-downgrade", record["check"])
+
+	b.Reset()
+	logger, err = NewLogger(&b, "DEBUG", LogFormatText)
+	assert.Nil(t, err)
+	logger.Debug("navigated", "url", "https://example.com/")
+	assert.Contains(t, b.String(), `level=DEBUG msg=navigated url=https://example.com/`)
+
+	_, err = NewLogger(&b, "verbose", LogFormatText)
+	assert.NotNil(t, err)
+	_, err = NewLogger(&b, "info", "xml")
+	assert.NotNil(t, err)
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"net/url"
 	"os"
+	"regexp"
 
 	"golang.org/x/oauth2"
 )
@@ -14,8 +15,29 @@ import (
 var OAuthConfig kOAuthConfig
 
 type endpointWrapper struct {
-	AuthURL  string `json:"auth_url"`
-	TokenURL string `json:"token_url"`
+	AuthURL                string `json:"auth_url"`
+	TokenURL               string `json:"token_url"`
+	DeviceAuthorizationURL string `json:"device_authorization_url"`
+	PARURL                 string `json:"par_url"`
+	RevocationURL          string `json:"revocation_url"`
+	IntrospectionURL       string `json:"introspection_url"`
+}
+
+type requestObjectWrapper struct {
+	SigningKey string `json:"signing_key"`
+	Alg        string `json:"alg"`
+	Kid        string `json:"kid"`
+	Audience   string `json:"audience"`
+}
+
+type resourceOwnerWrapper struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type clientWrapper struct {
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
 }
 
 type oAuthConfigWrapper struct {
@@ -24,14 +46,85 @@ type oAuthConfigWrapper struct {
 	Endpoint     endpointWrapper `json:"endpoint"`
 	RedirectURL  string          `json:"redirect_url"`
 	Scopes       []string        `json:"scopes"`
+
+	// Issuer identifier of the authorization server
+	Issuer string `json:"issuer"`
+
+	// Push every authorization request to the PAR endpoint
+	RequirePAR bool `json:"require_par"`
+
+	// Second client registered at the authorization server,
+	// used by checks involving more than one client
+	SecondaryClient *clientWrapper `json:"secondary_client"`
+
+	// Key used to sign JWT-secured authorization requests
+	RequestObject requestObjectWrapper `json:"request_object"`
+
+	// Protected resources which accept access tokens issued to the client
+	ResourceURLs []string `json:"resource_url"`
+
+	// Credentials of a test user for the resource owner password credentials grant
+	ResourceOwner resourceOwnerWrapper `json:"resource_owner"`
+
+	// Browser actions run on matching pages during flows
+	Hooks []Hook `json:"hooks"`
+
+	// Regular expression matching the URL of the authorization server's login page
+	LoginURLPattern string `json:"login_url_pattern"`
+
+	// Response mode sent in every authorization request, such as "form_post" or "query.jwt"
+	ResponseMode string `json:"response_mode"`
 }
 
 type kOAuthConfig struct {
 	OAuth2Config oauth2.Config
+
+	// Issuer identifier of the authorization server, as returned
+	// in the "iss" parameter defined in RFC 9207
+	Issuer string
+
+	// Device Authorization Endpoint as defined in RFC 8628
+	DeviceAuthorizationURL string
+
+	// Pushed Authorization Request Endpoint as defined in RFC 9126
+	PARURL     string
+	RequirePAR bool
+
+	// Config of the secondary client, nil if none was provided
+	SecondaryClient *oauth2.Config
+
+	// Request object signing options as defined in RFC 9101
+	RequestObjectSigningKey string // path to PEM encoded private key
+	RequestObjectAlg        string
+	RequestObjectKid        string
+	RequestObjectAudience   string
+
+	// Protected resources called with issued access tokens
+	ResourceURLs []string
+
+	// Token Revocation Endpoint as defined in RFC 7009
+	RevocationURL string
+
+	// Token Introspection Endpoint as defined in RFC 7662
+	IntrospectionURL string
+
+	// Credentials used by the resource owner password credentials grant
+	ResourceOwnerUsername string
+	ResourceOwnerPassword string
+
+	// Browser automation hooks, such as for consent pages
+	Hooks []Hook
+
+	// Flows ending on a page matching this pattern lost the session
+	LoginURLPattern *regexp.Regexp
+
+	// Response mode the client is registered to use, if not the default
+	// for the response type. Sent in every authorization request
+	ResponseMode string
 }
 
-// Get an oauth2 config from JSON file
-func readOAuthConfig(oauthConfigFile string, authStyle string) oauth2.Config {
+// Read oauth config wrapper from JSON file
+func readOAuthConfigFile(oauthConfigFile string) oAuthConfigWrapper {
 	jsonFile, err := os.Open(oauthConfigFile)
 	if err != nil {
 		panic("Error opening oauth config file")
@@ -46,7 +139,11 @@ func readOAuthConfig(oauthConfigFile string, authStyle string) oauth2.Config {
 	if err != nil {
 		panic("Error unmarshalling oauth config")
 	}
+	return conf
+}
 
+// Get an oauth2 config from the oauth config wrapper
+func newOAuth2Config(conf oAuthConfigWrapper, authStyle string) oauth2.Config {
 	var clientAuth oauth2.AuthStyle
 	switch authStyle {
 	case "BASIC":
@@ -88,7 +185,36 @@ func (c *kOAuthConfig) GetConfigHost() string {
 
 func newConfig(oauthConfigFile, authStyle string) kOAuthConfig {
 	conf := new(kOAuthConfig)
-	conf.OAuth2Config = readOAuthConfig(oauthConfigFile, authStyle)
+	wrapper := readOAuthConfigFile(oauthConfigFile)
+	conf.OAuth2Config = newOAuth2Config(wrapper, authStyle)
+	conf.Issuer = wrapper.Issuer
+	conf.DeviceAuthorizationURL = wrapper.Endpoint.DeviceAuthorizationURL
+	conf.PARURL = wrapper.Endpoint.PARURL
+	conf.RequirePAR = wrapper.RequirePAR
+	conf.RequestObjectSigningKey = wrapper.RequestObject.SigningKey
+	conf.RequestObjectAlg = wrapper.RequestObject.Alg
+	conf.RequestObjectKid = wrapper.RequestObject.Kid
+	conf.RequestObjectAudience = wrapper.RequestObject.Audience
+	conf.ResourceURLs = wrapper.ResourceURLs
+	conf.RevocationURL = wrapper.Endpoint.RevocationURL
+	conf.IntrospectionURL = wrapper.Endpoint.IntrospectionURL
+	conf.ResourceOwnerUsername = wrapper.ResourceOwner.Username
+	conf.ResourceOwnerPassword = wrapper.ResourceOwner.Password
+	conf.Hooks = compileHooks(wrapper.Hooks)
+	conf.ResponseMode = wrapper.ResponseMode
+	if wrapper.LoginURLPattern != "" {
+		pattern, err := regexp.Compile(wrapper.LoginURLPattern)
+		if err != nil {
+			log.Fatalf("Invalid login_url_pattern: %s\n", err)
+		}
+		conf.LoginURLPattern = pattern
+	}
+	if wrapper.SecondaryClient != nil {
+		secondary := conf.OAuth2Config
+		secondary.ClientID = wrapper.SecondaryClient.ClientID
+		secondary.ClientSecret = wrapper.SecondaryClient.ClientSecret
+		conf.SecondaryClient = &secondary
+	}
 	return *conf
 }
 
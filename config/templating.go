@@ -3,6 +3,7 @@ package config
 import (
 	"log"
 	"net/url"
+	"strings"
 
 	"github.com/hoisie/mustache"
 )
@@ -13,8 +14,24 @@ import (
 // checks so that check JSON input file can use
 // values, such as the domain of the redirect_uri
 // Supported keys: REDIRECT_URI, REDIRECT_SCHEME, REDIRECT_DOMAIN, REDIRECT_PATH,
-// CLIENT_ID, CLIENT_SECRET, SCOPES, AUTH_URL, TOKEN_URL
+// REDIRECT_HOSTNAME (REDIRECT_DOMAIN without the port), REDIRECT_PORT, REDIRECT_SCHEME_SPECIFIC
+// (REDIRECT_URI without the scheme and colon, such as "/callback" for com.example.app:/callback),
+// CLIENT_ID, CLIENT_SECRET, SCOPES, AUTH_URL, TOKEN_URL, DEVICE_AUTH_URL, PAR_URL,
+// SECONDARY_CLIENT_ID, SECONDARY_CLIENT_SECRET, ISSUER, RESOURCE_URL (the first resource_url),
+// REVOCATION_URL, INTROSPECTION_URL
 func GenerateChecksInput(configFile string) []byte {
+	data := mustache.RenderFile(configFile, checksTemplateKeys())
+	return []byte(data)
+}
+
+// RenderChecksInput - same as GenerateChecksInput, for check JSON which is not read from a file
+func RenderChecksInput(checksJSON string) []byte {
+	data := mustache.Render(checksJSON, checksTemplateKeys())
+	return []byte(data)
+}
+
+// values from the config file available to check JSON input
+func checksTemplateKeys() map[string]interface{} {
 	templateKeyMap := make(map[string]interface{})
 	redirectURI, err := url.Parse(OAuthConfig.OAuth2Config.RedirectURL)
 	if err != nil {
@@ -25,14 +42,25 @@ func GenerateChecksInput(configFile string) []byte {
 	templateKeyMap["REDIRECT_SCHEME"] = redirectURI.Scheme
 	templateKeyMap["REDIRECT_DOMAIN"] = redirectURI.Host
 	templateKeyMap["REDIRECT_PATH"] = redirectURI.Path
+	templateKeyMap["REDIRECT_HOSTNAME"] = redirectURI.Hostname()
+	templateKeyMap["REDIRECT_PORT"] = redirectURI.Port()
+	templateKeyMap["REDIRECT_SCHEME_SPECIFIC"] = strings.TrimPrefix(redirectURI.String(), redirectURI.Scheme+":")
 	templateKeyMap["CLIENT_ID"] = OAuthConfig.OAuth2Config.ClientID
 	templateKeyMap["CLIENT_SECRET"] = OAuthConfig.OAuth2Config.ClientSecret
 	templateKeyMap["SCOPES"] = OAuthConfig.OAuth2Config.Scopes
 	templateKeyMap["AUTH_URL"] = OAuthConfig.OAuth2Config.Endpoint.AuthURL
 	templateKeyMap["TOKEN_URL"] = OAuthConfig.OAuth2Config.Endpoint.TokenURL
-
-	data := mustache.RenderFile(configFile, templateKeyMap)
-	bytes := []byte(data)
-	return bytes
-
+	templateKeyMap["DEVICE_AUTH_URL"] = OAuthConfig.DeviceAuthorizationURL
+	templateKeyMap["PAR_URL"] = OAuthConfig.PARURL
+	templateKeyMap["ISSUER"] = OAuthConfig.Issuer
+	templateKeyMap["REVOCATION_URL"] = OAuthConfig.RevocationURL
+	templateKeyMap["INTROSPECTION_URL"] = OAuthConfig.IntrospectionURL
+	if len(OAuthConfig.ResourceURLs) > 0 {
+		templateKeyMap["RESOURCE_URL"] = OAuthConfig.ResourceURLs[0]
+	}
+	if OAuthConfig.SecondaryClient != nil {
+		templateKeyMap["SECONDARY_CLIENT_ID"] = OAuthConfig.SecondaryClient.ClientID
+		templateKeyMap["SECONDARY_CLIENT_SECRET"] = OAuthConfig.SecondaryClient.ClientSecret
+	}
+	return templateKeyMap
 }
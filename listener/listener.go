@@ -0,0 +1,110 @@
+package listener
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Request - a request received by the listener
+type Request struct {
+	Method     string
+	Path       string
+	RawQuery   string
+	RemoteAddr string
+	UserAgent  string
+	Header     http.Header
+	Body       string
+	Time       time.Time
+}
+
+// Listener - local HTTP server which records every request it receives.
+// Used to detect requests made by the authorization server, and to
+// serve content such as request objects or attacker metadata
+type Listener struct {
+	server   *http.Server
+	baseURL  string
+	mu       sync.Mutex
+	requests []Request
+	handlers map[string]http.HandlerFunc
+}
+
+// Start - starts a listener on the provided address. publicURL is the URL
+// the authorization server can reach the listener at, if empty the
+// address the listener is bound to is used
+func Start(addr, publicURL string) (*Listener, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Listener{
+		baseURL:  strings.TrimSuffix(publicURL, "/"),
+		handlers: make(map[string]http.HandlerFunc),
+	}
+	if l.baseURL == "" {
+		l.baseURL = "http://" + ln.Addr().String()
+	}
+	l.server = &http.Server{Handler: l}
+	go l.server.Serve(ln)
+	return l, nil
+}
+
+// URL - URL of the provided path on the listener
+func (l *Listener) URL(path string) string {
+	return l.baseURL + path
+}
+
+// Handle - serve requests to the provided path with a handler
+func (l *Listener) Handle(path string, h http.HandlerFunc) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.handlers[path] = h
+}
+
+// Requests - requests received for the provided path
+func (l *Listener) Requests(path string) []Request {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var ret []Request
+	for _, r := range l.requests {
+		if r.Path == path {
+			ret = append(ret, r)
+		}
+	}
+	return ret
+}
+
+// Close - stop the listener
+func (l *Listener) Close() error {
+	return l.server.Close()
+}
+
+// ServeHTTP - records the request and passes it to the handler for its path
+func (l *Listener) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	r.Body = ioutil.NopCloser(strings.NewReader(string(body)))
+
+	l.mu.Lock()
+	l.requests = append(l.requests, Request{
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		RawQuery:   r.URL.RawQuery,
+		RemoteAddr: r.RemoteAddr,
+		UserAgent:  r.UserAgent(),
+		Header:     r.Header.Clone(),
+		Body:       string(body),
+		Time:       time.Now(),
+	})
+	h, ok := l.handlers[r.URL.Path]
+	l.mu.Unlock()
+
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	h(w, r)
+}
@@ -0,0 +1,76 @@
+package oauth
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+
+	"golang.org/x/oauth2"
+)
+
+// newClientAuthRequest - creates a POST request to an authorization server endpoint,
+// authenticating with the client credentials of the provided oauth2 config
+func newClientAuthRequest(ctx context.Context, conf *oauth2.Config, endpoint string, v url.Values) (*http.Request, error) {
+	form := url.Values{}
+	for k, vals := range v {
+		form[k] = append([]string(nil), vals...)
+	}
+	if form.Get(ClientIDParam) == "" && conf.ClientID != "" {
+		form.Set(ClientIDParam, conf.ClientID)
+	}
+
+	inHeader := conf.ClientSecret != "" && conf.Endpoint.AuthStyle != oauth2.AuthStyleInParams
+	if conf.ClientSecret != "" && !inHeader {
+		form.Set(ClientSecretParam, conf.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if inHeader {
+		req.SetBasicAuth(url.QueryEscape(conf.ClientID), url.QueryEscape(conf.ClientSecret))
+	}
+	return req, nil
+}
+
+// doBackChannelRequest - performs an HTTP request to the authorization server,
+// returning a dump of the request and response along with the response body
+func doBackChannelRequest(client *http.Client, req *http.Request) (*ExchangeRequest, []byte, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	er := &ExchangeRequest{Request: req}
+	reqBytes, err := httputil.DumpRequest(req, true)
+	if err != nil {
+		slog.Warn("Could not dump request", "err", err)
+	}
+	er.RequestString = string(reqBytes)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return er, nil, err
+	}
+	defer resp.Body.Close()
+	er.Response = resp
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return er, nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewBuffer(body))
+	respBytes, err := httputil.DumpResponse(resp, true)
+	if err != nil {
+		slog.Warn("Could not dump response", "err", err)
+	}
+	er.ResponseString = string(respBytes)
+	resp.Body = ioutil.NopCloser(bytes.NewBuffer(body))
+
+	return er, body, nil
+}
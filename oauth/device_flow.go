@@ -0,0 +1,179 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"github.com/morganc3/KOAuth/browser"
+	"github.com/morganc3/KOAuth/config"
+	"golang.org/x/oauth2"
+)
+
+// default polling interval in seconds, as defined in rfc8628
+const defaultDevicePollingInterval = 5
+
+// DeviceAuthorization - Represents a device authorization response as defined in RFC 8628
+type DeviceAuthorization struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete,omitempty"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval,omitempty"`
+
+	// when the device authorization was requested
+	requested time.Time
+}
+
+// Expiry - when the device code expires. If the response did not contain
+// expires_in, the fallback duration after the request is used
+func (da *DeviceAuthorization) Expiry(fallback time.Duration) time.Time {
+	if da.ExpiresIn > 0 {
+		return da.requested.Add(time.Duration(da.ExpiresIn) * time.Second)
+	}
+	return da.requested.Add(fallback)
+}
+
+// PollingInterval - minimum time in seconds to wait between polling requests
+func (da *DeviceAuthorization) PollingInterval() int {
+	if da.Interval > 0 {
+		return da.Interval
+	}
+	return defaultDevicePollingInterval
+}
+
+// DefaultDeviceAuthorizationParams - parameters sent in a device authorization
+// request based on the values from the client's oauth config
+func (i *FlowInstance) DefaultDeviceAuthorizationParams() url.Values {
+	conf := i.ClientConfig()
+	v := url.Values{}
+	v.Set(ClientIDParam, conf.ClientID)
+	if len(conf.Scopes) > 0 {
+		v.Set(ScopeParam, strings.Join(conf.Scopes, " "))
+	}
+	return v
+}
+
+// RequestDeviceAuthorization - Perform a device authorization request
+// with the provided parameters, storing the response in the flow instance
+func (i *FlowInstance) RequestDeviceAuthorization(ctx context.Context, v url.Values) error {
+	endpoint := config.OAuthConfig.DeviceAuthorizationURL
+	if endpoint == "" {
+		return errors.New("No device_authorization_url provided in config file")
+	}
+
+	req, err := newClientAuthRequest(ctx, i.ClientConfig(), endpoint, v)
+	if err != nil {
+		return err
+	}
+	requested := time.Now()
+	er, body, err := doBackChannelRequest(nil, req)
+	i.DeviceAuthorizationRequest = er
+	if err != nil {
+		return err
+	}
+	if er.Response.StatusCode != http.StatusOK {
+		return fmt.Errorf("Device authorization request failed: %s %s", er.Response.Status, getErrorCodeFromBody(body))
+	}
+
+	var da DeviceAuthorization
+	err = json.Unmarshal(body, &da)
+	if err != nil {
+		return err
+	}
+	if da.DeviceCode == "" || da.UserCode == "" {
+		return errors.New("Device authorization response is missing device_code or user_code")
+	}
+	da.requested = requested
+	i.DeviceAuthorization = &da
+	return nil
+}
+
+// DoDeviceVerification - browse to the verification page in the authenticated
+// browser session. If the authorization server did not provide a
+// verification_uri_complete, the user_code is entered into the page. The device is
+// approved by hooks for the verification page, or by the user. If the flow waits
+// for a condition, such as a page shown once the device was approved, the tab
+// must meet it before the device code expires
+func (i *FlowInstance) DoDeviceVerification() error {
+	da := i.DeviceAuthorization
+	if da == nil {
+		return errors.New("No device authorization was requested")
+	}
+
+	var actions []chromedp.Action
+	if da.VerificationURIComplete != "" {
+		actions = append(actions, chromedp.Navigate(da.VerificationURIComplete))
+	} else {
+		actions = append(actions,
+			chromedp.Navigate(da.VerificationURI),
+			chromedp.SendKeys(`input:not([type=hidden])`, da.UserCode+"\r", chromedp.ByQuery),
+		)
+	}
+
+	i.watchTab()
+	defer i.stopRedirectChain()
+	_, cancel, err := browser.RunWithTimeOut(&i.Ctx, i.Options.Timeout, actions)
+	cancel()
+	if err != nil || i.WaitFor == nil {
+		return err
+	}
+
+	waitCtx, cancel := context.WithDeadline(i.Ctx, da.Expiry(i.Options.Timeout))
+	defer cancel()
+	select {
+	case <-browser.WaitFor(waitCtx, *i.WaitFor):
+		return nil
+	case <-waitCtx.Done():
+		return errors.New("Verification page did not meet the waitFor condition before the device code expired")
+	}
+}
+
+// PollDeviceToken - polls the token endpoint with the device code until
+// the device authorization is approved, denied or expires. Polling stops once
+// the device code expires, or after the flow timeout if the expiry is unknown,
+// but will always poll at least once.
+// "slow_down" responses increase the polling interval as defined in rfc8628
+func (i *FlowInstance) PollDeviceToken(ctx context.Context, v url.Values) (*oauth2.Token, error) {
+	interval := defaultDevicePollingInterval
+	deadline := time.Now().Add(i.Options.Timeout)
+	if da := i.DeviceAuthorization; da != nil {
+		interval = da.PollingInterval()
+		deadline = da.Expiry(i.Options.Timeout)
+	}
+	for {
+		time.Sleep(time.Duration(interval) * time.Second)
+
+		tok, err := i.Exchange(ctx, v)
+		switch GetTokenErrorCode(err) {
+		case AuthorizationPendingError:
+		case SlowDownError:
+			interval += defaultDevicePollingInterval
+		default:
+			return tok, err
+		}
+
+		if time.Now().After(deadline) {
+			return nil, err
+		}
+	}
+}
+
+// DefaultDeviceTokenParams - parameters sent to the token endpoint
+// when polling with the device code of this flow instance
+func (i *FlowInstance) DefaultDeviceTokenParams() url.Values {
+	v := url.Values{}
+	v.Set(GrantTypeParam, DeviceCodeGrantType)
+	if i.DeviceAuthorization != nil {
+		v.Set(DeviceCodeParam, i.DeviceAuthorization.DeviceCode)
+	}
+	v.Set(ClientIDParam, i.ClientConfig().ClientID)
+	return v
+}
@@ -0,0 +1,18 @@
+package oauth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeviceAuthorizationExpiry(t *testing.T) {
+	requested := time.Now()
+	da := &DeviceAuthorization{ExpiresIn: 600, requested: requested}
+	assert.Equal(t, requested.Add(10*time.Minute), da.Expiry(4*time.Second))
+
+	// expires_in is required, but the flow timeout is used if it is missing
+	da.ExpiresIn = 0
+	assert.Equal(t, requested.Add(4*time.Second), da.Expiry(4*time.Second))
+}
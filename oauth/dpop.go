@@ -0,0 +1,138 @@
+package oauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// DPoP header and error constants, as defined in RFC 9449
+const (
+	DPoPHeader      = "DPoP"
+	DPoPNonceHeader = "DPoP-Nonce"
+	DPoPTokenType   = "DPoP"
+	UseDPoPNonce    = "use_dpop_nonce"
+)
+
+// DPoPOptions - values overriding those normally used in DPoP proofs.
+// Used by checks sending proofs that should be rejected
+type DPoPOptions struct {
+	HTU string `json:"htu,omitempty"`
+	HTM string `json:"htm,omitempty"`
+	JTI string `json:"jti,omitempty"`
+	// proof sent unchanged instead of creating one, to replay a proof sent earlier
+	Proof string `json:"proof,omitempty"`
+}
+
+// EnableDPoP - generates a key pair for this flow and sends
+// DPoP proofs with requests to the token endpoint
+func (i *FlowInstance) EnableDPoP(opts DPoPOptions) error {
+	if i.DPoPKey == nil {
+		key, err := GenerateSigningKey(AlgES256)
+		if err != nil {
+			return err
+		}
+		i.DPoPKey = key
+	}
+	i.DPoPOptions = opts
+	return nil
+}
+
+// DPoPProof - creates a DPoP proof for a request. If an access token is
+// provided, its hash is included as the "ath" claim
+func (i *FlowInstance) DPoPProof(method string, u *url.URL, accessToken, nonce string) (string, error) {
+	if i.DPoPOptions.Proof != "" {
+		i.DPoPLastProof = i.DPoPOptions.Proof
+		return i.DPoPOptions.Proof, nil
+	}
+
+	jwk, err := i.DPoPKey.PublicJWK()
+	if err != nil {
+		return "", err
+	}
+
+	htu := *u
+	htu.RawQuery = ""
+	htu.Fragment = ""
+	claims := map[string]interface{}{
+		"jti": randStr(32),
+		"htm": method,
+		"htu": htu.String(),
+		"iat": time.Now().Unix(),
+	}
+	if i.DPoPOptions.HTU != "" {
+		claims["htu"] = i.DPoPOptions.HTU
+	}
+	if i.DPoPOptions.HTM != "" {
+		claims["htm"] = i.DPoPOptions.HTM
+	}
+	if i.DPoPOptions.JTI != "" {
+		claims["jti"] = i.DPoPOptions.JTI
+	}
+	if accessToken != "" {
+		ath := sha256.Sum256([]byte(accessToken))
+		claims["ath"] = b64(ath[:])
+	}
+	if nonce != "" {
+		claims["nonce"] = nonce
+	}
+	i.DPoPJTI = claims["jti"].(string)
+
+	header := map[string]interface{}{"typ": "dpop+jwt", "jwk": jwk}
+	proof, err := SignJWT(i.DPoPKey, header, claims)
+	if err != nil {
+		return "", err
+	}
+	i.DPoPLastProof = proof
+	return proof, nil
+}
+
+// adds DPoP proofs to token endpoint requests made by the oauth2 package
+func (i *FlowInstance) withDPoP(ctx context.Context) context.Context {
+	if i.DPoPKey == nil {
+		return ctx
+	}
+	client := &http.Client{Transport: &dpopTransport{fi: i}}
+	return context.WithValue(ctx, oauth2.HTTPClient, client)
+}
+
+// dpopTransport - adds a DPoP proof to each request. If the server
+// requires a nonce, the request is retried once with it
+type dpopTransport struct {
+	fi *FlowInstance
+}
+
+func (t *dpopTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.roundTrip(req, "")
+	if err != nil {
+		return resp, err
+	}
+
+	nonce := resp.Header.Get(DPoPNonceHeader)
+	if nonce == "" || resp.StatusCode != http.StatusBadRequest || req.GetBody == nil {
+		return resp, err
+	}
+	resp.Body.Close()
+
+	retry := req.Clone(req.Context())
+	retry.Body, err = req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	return t.roundTrip(retry, nonce)
+}
+
+// sends a copy of the request with a DPoP proof, as a RoundTripper must not modify the request
+func (t *dpopTransport) roundTrip(req *http.Request, nonce string) (*http.Response, error) {
+	proof, err := t.fi.DPoPProof(req.Method, req.URL, "", nonce)
+	if err != nil {
+		return nil, err
+	}
+	withProof := req.Clone(req.Context())
+	withProof.Header.Set(DPoPHeader, proof)
+	return http.DefaultTransport.RoundTrip(withProof)
+}
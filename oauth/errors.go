@@ -1,8 +1,12 @@
 package oauth
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net/url"
+
+	"golang.org/x/oauth2"
 )
 
 // error constants
@@ -12,6 +16,23 @@ const (
 	NotRedirectedError    = "Browser was never redirected to the provided redirect_uri"
 )
 
+// token endpoint error codes, as defined in rfc6749 and rfc8628
+const (
+	InvalidGrantError         = "invalid_grant"
+	UnsupportedGrantTypeError = "unsupported_grant_type"
+	UnauthorizedClientError   = "unauthorized_client"
+	AuthorizationPendingError = "authorization_pending"
+	SlowDownError             = "slow_down"
+	AccessDeniedError         = "access_denied"
+	ExpiredTokenError         = "expired_token"
+)
+
+// authorization error codes indicating the session was lost, as defined in OpenID Connect Core
+const (
+	LoginRequiredError       = "login_required"
+	InteractionRequiredError = "interaction_required"
+)
+
 // GetURLError - gets error from URL parameter as defined in the OAuth 2.0 specification
 func (i *FlowInstance) GetURLError() error {
 	if i.RedirectedToURL == nil {
@@ -26,3 +47,28 @@ func (i *FlowInstance) GetURLError() error {
 	}
 	return nil
 }
+
+// GetTokenErrorCode - gets the error code from an error response of the
+// token endpoint as defined in the OAuth 2.0 specification
+func GetTokenErrorCode(err error) string {
+	rErr, ok := err.(*oauth2.RetrieveError)
+	if !ok {
+		return ""
+	}
+	return getErrorCodeFromBody(rErr.Body)
+}
+
+// gets the "error" field from a JSON or form encoded response body
+func getErrorCodeFromBody(body []byte) string {
+	var errResp struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &errResp); err == nil {
+		return errResp.Error
+	}
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return ""
+	}
+	return values.Get(ErrorParam)
+}
@@ -0,0 +1,32 @@
+package oauth
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/morganc3/KOAuth/config"
+)
+
+// DefaultClientCredentialsParams - parameters sent to the token endpoint for
+// the client credentials grant, requesting the scopes from the oauth config
+func (i *FlowInstance) DefaultClientCredentialsParams() url.Values {
+	v := url.Values{}
+	v.Set(GrantTypeParam, ClientCredentialsGrantType)
+	if scopes := i.ClientConfig().Scopes; len(scopes) > 0 {
+		v.Set(ScopeParam, strings.Join(scopes, " "))
+	}
+	return v
+}
+
+// DefaultPasswordParams - parameters sent to the token endpoint for the resource
+// owner password credentials grant, using the resource owner from the oauth config
+func (i *FlowInstance) DefaultPasswordParams() url.Values {
+	v := url.Values{}
+	v.Set(GrantTypeParam, PasswordGrantType)
+	v.Set(UsernameParam, config.OAuthConfig.ResourceOwnerUsername)
+	v.Set(PasswordParam, config.OAuthConfig.ResourceOwnerPassword)
+	if scopes := i.ClientConfig().Scopes; len(scopes) > 0 {
+		v.Set(ScopeParam, strings.Join(scopes, " "))
+	}
+	return v
+}
@@ -0,0 +1,76 @@
+package oauth
+
+import (
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/morganc3/KOAuth/config"
+)
+
+// lifetime of request objects we sign
+const requestObjectLifetime = 5 * time.Minute
+
+// ConfiguredRequestObjectKey - loads the request object signing key from the oauth config
+func ConfiguredRequestObjectKey() (*SigningKey, error) {
+	alg := config.OAuthConfig.RequestObjectAlg
+	if alg == "" {
+		alg = AlgRS256
+	}
+	if config.OAuthConfig.RequestObjectSigningKey == "" && alg != AlgNone {
+		return nil, errors.New("No request_object signing_key provided in config file")
+	}
+	return LoadSigningKey(config.OAuthConfig.RequestObjectSigningKey, alg, config.OAuthConfig.RequestObjectKid)
+}
+
+// SignRequestObject - signs the parameters of the Authorization URL into a request
+// object. Extra claims are added to, or replace, the claims taken from the
+// parameters. The Authorization URL is replaced with one containing only
+// the client_id and the request object
+func (i *FlowInstance) SignRequestObject(key *SigningKey, extraClaims map[string]interface{}) (string, error) {
+	conf := i.ClientConfig()
+	claims := make(map[string]interface{})
+	for k, v := range i.AuthorizationURL.Query() {
+		if len(v) > 0 {
+			claims[k] = v[0]
+		}
+	}
+
+	now := time.Now()
+	claims["iss"] = conf.ClientID
+	claims["aud"] = requestObjectAudience()
+	claims["iat"] = now.Unix()
+	claims["nbf"] = now.Unix()
+	claims["exp"] = now.Add(requestObjectLifetime).Unix()
+	claims["jti"] = randStr(32)
+	for k, v := range extraClaims {
+		claims[k] = v
+	}
+
+	jwt, err := SignJWT(key, map[string]interface{}{"typ": "oauth-authz-req+jwt"}, claims)
+	if err != nil {
+		return "", err
+	}
+
+	q := url.Values{}
+	q.Set(ClientIDParam, conf.ClientID)
+	q.Set(RequestParam, jwt)
+	i.AuthorizationURL.RawQuery = q.Encode()
+	return jwt, nil
+}
+
+// audience of request objects, defaults to the issuer
+// or the origin of the Auth URL if no issuer was provided
+func requestObjectAudience() string {
+	if config.OAuthConfig.RequestObjectAudience != "" {
+		return config.OAuthConfig.RequestObjectAudience
+	}
+	if config.OAuthConfig.Issuer != "" {
+		return config.OAuthConfig.Issuer
+	}
+	u, err := url.Parse(config.OAuthConfig.OAuth2Config.Endpoint.AuthURL)
+	if err != nil {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
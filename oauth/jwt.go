@@ -0,0 +1,213 @@
+package oauth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"strings"
+)
+
+// JWS algorithms supported for signing
+const (
+	AlgNone  = "none"
+	AlgRS256 = "RS256"
+	AlgPS256 = "PS256"
+	AlgES256 = "ES256"
+)
+
+// SigningKey - private key and algorithm used to sign JWTs
+type SigningKey struct {
+	Alg string
+	Kid string
+	Key crypto.Signer // nil when Alg is "none"
+}
+
+// LoadSigningKey - reads a PEM encoded PKCS#8, PKCS#1 or EC private key from a file
+func LoadSigningKey(path, alg, kid string) (*SigningKey, error) {
+	if alg == AlgNone {
+		return &SigningKey{Alg: alg, Kid: kid}, nil
+	}
+	pemBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, fmt.Errorf("No PEM data found in %s", path)
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("Unsupported private key type in %s", path)
+	}
+	return &SigningKey{Alg: alg, Kid: kid, Key: signer}, nil
+}
+
+// GenerateSigningKey - generates a new key for the provided algorithm
+func GenerateSigningKey(alg string) (*SigningKey, error) {
+	var signer crypto.Signer
+	var err error
+	switch alg {
+	case AlgNone:
+	case AlgRS256, AlgPS256:
+		signer, err = rsa.GenerateKey(rand.Reader, 2048)
+	case AlgES256:
+		signer, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	default:
+		return nil, fmt.Errorf("Unsupported JWS algorithm %s", alg)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &SigningKey{Alg: alg, Key: signer}, nil
+}
+
+// SignJWT - creates a compact serialized JWS with the provided claims. Extra header
+// parameters are added alongside "alg" and "kid"
+func SignJWT(key *SigningKey, header, claims map[string]interface{}) (string, error) {
+	h := map[string]interface{}{"alg": key.Alg}
+	if key.Kid != "" {
+		h["kid"] = key.Kid
+	}
+	for k, v := range header {
+		h[k] = v
+	}
+
+	headerJSON, err := json.Marshal(h)
+	if err != nil {
+		return "", err
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := b64(headerJSON) + "." + b64(claimsJSON)
+
+	sig, err := key.sign([]byte(signingInput))
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + b64(sig), nil
+}
+
+// DecodeJWTClaims - decodes the claims of a compact serialized JWT
+// without verifying its signature
+func DecodeJWTClaims(jwt string) (map[string]interface{}, error) {
+	return decodeJWTPart(jwt, 1)
+}
+
+// DecodeJWTHeader - decodes the header of a compact serialized JWT
+func DecodeJWTHeader(jwt string) (map[string]interface{}, error) {
+	return decodeJWTPart(jwt, 0)
+}
+
+func decodeJWTPart(jwt string, part int) (map[string]interface{}, error) {
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("Malformed JWT")
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(parts[part])
+	if err != nil {
+		return nil, err
+	}
+	var values map[string]interface{}
+	err = json.Unmarshal(decoded, &values)
+	return values, err
+}
+
+// PublicJWK - public key of the signing key as a JSON Web Key
+func (k *SigningKey) PublicJWK() (map[string]interface{}, error) {
+	if k.Key == nil {
+		return nil, errors.New("No signing key provided")
+	}
+	switch pub := k.Key.Public().(type) {
+	case *rsa.PublicKey:
+		return map[string]interface{}{
+			"kty": "RSA",
+			"n":   b64(pub.N.Bytes()),
+			"e":   b64(big.NewInt(int64(pub.E)).Bytes()),
+		}, nil
+	case *ecdsa.PublicKey:
+		return map[string]interface{}{
+			"kty": "EC",
+			"crv": "P-256",
+			"x":   b64(padBytes(pub.X, 32)),
+			"y":   b64(padBytes(pub.Y, 32)),
+		}, nil
+	}
+	return nil, errors.New("Unsupported public key type")
+}
+
+// signs the JWS signing input with the key's algorithm
+func (k *SigningKey) sign(input []byte) ([]byte, error) {
+	if k.Alg == AlgNone {
+		return []byte{}, nil
+	}
+	if k.Key == nil {
+		return nil, errors.New("No signing key provided")
+	}
+	digest := sha256.Sum256(input)
+
+	switch k.Alg {
+	case AlgRS256:
+		rsaKey, ok := k.Key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, errors.New("RS256 requires an RSA key")
+		}
+		return rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+	case AlgPS256:
+		rsaKey, ok := k.Key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, errors.New("PS256 requires an RSA key")
+		}
+		opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}
+		return rsa.SignPSS(rand.Reader, rsaKey, crypto.SHA256, digest[:], opts)
+	case AlgES256:
+		ecKey, ok := k.Key.(*ecdsa.PrivateKey)
+		if !ok {
+			return nil, errors.New("ES256 requires an EC key")
+		}
+		r, s, err := ecdsa.Sign(rand.Reader, ecKey, digest[:])
+		if err != nil {
+			return nil, err
+		}
+		// JWS uses the fixed length concatenation of r and s
+		return append(padBytes(r, 32), padBytes(s, 32)...), nil
+	}
+	return nil, fmt.Errorf("Unsupported JWS algorithm %s", k.Alg)
+}
+
+func padBytes(n *big.Int, size int) []byte {
+	b := n.Bytes()
+	if len(b) >= size {
+		return b
+	}
+	return append(make([]byte, size-len(b)), b...)
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,98 @@
+package oauth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignJWT(t *testing.T) {
+	claims := map[string]interface{}{"iss": "client", "redirect_uri": "https://example.com"}
+
+	for _, alg := range []string{AlgRS256, AlgPS256, AlgES256} {
+		key, err := GenerateSigningKey(alg)
+		assert.Nil(t, err)
+
+		jwt, err := SignJWT(key, nil, claims)
+		assert.Nil(t, err)
+		parts := strings.Split(jwt, ".")
+		assert.Len(t, parts, 3)
+
+		sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+		assert.Nil(t, err)
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+
+		switch alg {
+		case AlgRS256:
+			pub := key.Key.Public().(*rsa.PublicKey)
+			assert.Nil(t, rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig))
+		case AlgPS256:
+			pub := key.Key.Public().(*rsa.PublicKey)
+			assert.Nil(t, rsa.VerifyPSS(pub, crypto.SHA256, digest[:], sig, nil))
+		case AlgES256:
+			pub := key.Key.Public().(*ecdsa.PublicKey)
+			assert.Len(t, sig, 64)
+			r := new(big.Int).SetBytes(sig[:32])
+			s := new(big.Int).SetBytes(sig[32:])
+			assert.True(t, ecdsa.Verify(pub, digest[:], r, s))
+		}
+
+		decoded, err := DecodeJWTClaims(jwt)
+		assert.Nil(t, err)
+		assert.Equal(t, "https://example.com", decoded["redirect_uri"])
+	}
+
+	unsigned, err := SignJWT(&SigningKey{Alg: AlgNone}, nil, claims)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasSuffix(unsigned, "."))
+}
+
+func TestDPoPProofReplay(t *testing.T) {
+	i := &FlowInstance{}
+	assert.Nil(t, i.EnableDPoP(DPoPOptions{}))
+	u, _ := url.Parse("https://as.example.com/token?a=b")
+	proof, err := i.DPoPProof("POST", u, "", "")
+	assert.Nil(t, err)
+	assert.Equal(t, proof, i.DPoPLastProof)
+
+	// a replayed proof is sent unchanged, even by a flow with another key
+	replay := &FlowInstance{}
+	assert.Nil(t, replay.EnableDPoP(DPoPOptions{Proof: proof}))
+	sent, err := replay.DPoPProof("POST", u, "", "nonce")
+	assert.Nil(t, err)
+	assert.Equal(t, proof, sent)
+}
+
+func TestDPoPTransport(t *testing.T) {
+	var proofs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proofs = append(proofs, r.Header.Get(DPoPHeader))
+		if len(proofs) == 1 {
+			w.Header().Set(DPoPNonceHeader, "nonce")
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	i := &FlowInstance{}
+	assert.Nil(t, i.EnableDPoP(DPoPOptions{}))
+	req, _ := http.NewRequest("POST", server.URL, strings.NewReader("grant_type=authorization_code"))
+	resp, err := (&dpopTransport{fi: i}).RoundTrip(req)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// the nonce is retried with a new proof, and the caller's request is not modified
+	assert.Len(t, proofs, 2)
+	assert.NotEqual(t, proofs[0], proofs[1])
+	assert.Empty(t, req.Header.Get(DPoPHeader))
+}
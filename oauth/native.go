@@ -0,0 +1,172 @@
+package oauth
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/morganc3/KOAuth/browser"
+)
+
+// maximum number of redirects followed by native authorization requests
+const maxNativeRedirects = 20
+
+// errNeedsBrowser - a page was served which must be loaded in the browser, as it may
+// need JavaScript, a form submission (such as response_mode=form_post) or user interaction
+var errNeedsBrowser = errors.New("authorization server served a page which must be loaded in the browser")
+
+// tries the authorization request without the browser, if enabled. Returns
+// true if it was completed, in which case the error is the result of the request
+func (i *FlowInstance) tryNativeAuthorizationRequest() (bool, error) {
+	if !i.nativeAllowed() {
+		return false, nil
+	}
+
+	cookies, err := browser.Cookies(i.Ctx)
+	if err != nil {
+		i.logger().Warn("Could not get cookies from the browser for a native request", "err", err)
+		return false, nil
+	}
+	client := nativeClient(browser.CookieJar(cookies), i.Options.Proxy, i.Options.Timeout)
+
+	err = i.nativeAuthorizationRequest(client, i.Options.UserAgent)
+	if errors.Is(err, errNeedsBrowser) {
+		i.resetNativeResult()
+		return false, nil
+	}
+	return true, err
+}
+
+// nativeAllowed - true if native requests are enabled and the request can be sent twice.
+// request_uri values and the jti of request objects can only be used once, so they must
+// not be used by a native request which falls back to the browser
+func (i *FlowInstance) nativeAllowed() bool {
+	if !i.Options.Native || i.PushedAuthorization != nil {
+		return false
+	}
+	for _, p := range []string{RequestParam, RequestURIParam} {
+		if GetQueryParameterFirst(i.AuthorizationURL, p) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// HTTP client which does not follow redirects, using the same proxy as the browser
+func nativeClient(jar http.CookieJar, proxy string, timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if proxy != "" {
+		// Be sure you trust your proxy server if you choose this option
+		transport.Proxy = http.ProxyURL(&url.URL{Scheme: "http", Host: proxy})
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	return &http.Client{
+		Jar:       jar,
+		Transport: transport,
+		Timeout:   timeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// nativeAuthorizationRequest - performs the authorization request with an HTTP client,
+// following redirects until the redirect_uri is reached. Cookies set by the authorization
+// server are stored in the browser. Pages which respond with an error status code are
+// treated as error pages, and errNeedsBrowser is returned for any other page
+func (i *FlowInstance) nativeAuthorizationRequest(client *http.Client, userAgent string) error {
+	u := i.AuthorizationURL
+	for n := 0; n <= maxNativeRedirects; n++ {
+		if i.isRedirectURI(u) {
+			i.nativeHops = append(i.nativeHops, browser.Hop{URL: u.String()})
+			i.RedirectedToURL = u
+			i.setRedirectOutcome(u, nil)
+			return i.GetURLError() // get error as defined in rfc6749
+		}
+
+		resp, err := nativeGet(i.Ctx, client, u, userAgent)
+		if err != nil {
+			i.logger().Debug("Native request failed, falling back to the browser", "url", u.String(), "err", err)
+			return errNeedsBrowser
+		}
+		i.nativeHops = append(i.nativeHops, browser.Hop{URL: u.String(), Status: int64(resp.StatusCode)})
+		i.logger().Debug("Native request", "status", resp.StatusCode, "url", u.String())
+		i.storeNativeCookies(u, resp.Cookies())
+
+		location := resp.Header.Get("Location")
+		if isRedirectStatus(resp.StatusCode) && location != "" {
+			next, err := u.Parse(location)
+			if err != nil {
+				return errNeedsBrowser
+			}
+			u = next
+			continue
+		}
+
+		if resp.StatusCode >= 400 {
+			i.LastURL = u
+			i.Outcome = OutcomeErrorPage
+			return nil
+		}
+		return errNeedsBrowser
+	}
+	return errNeedsBrowser
+}
+
+func nativeGet(ctx context.Context, client *http.Client, u *url.URL, userAgent string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	// only the status code and headers are used
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 1<<20))
+	resp.Body.Close()
+	return resp, nil
+}
+
+func (i *FlowInstance) storeNativeCookies(u *url.URL, cookies []*http.Cookie) {
+	if len(cookies) == 0 {
+		return
+	}
+	err := browser.StoreCookies(i.Ctx, u, cookies)
+	if err != nil {
+		i.logger().Warn("Could not store cookies from a native request in the browser", "err", err)
+	}
+}
+
+// clears the result of a native authorization request before falling back to the browser
+func (i *FlowInstance) resetNativeResult() {
+	i.nativeHops = nil
+	i.RedirectedToURL = new(url.URL)
+	i.LastURL = nil
+	i.AuthorizationResponse = nil
+	i.ResponseMode = ""
+	i.JARM = nil
+	i.Outcome = ""
+	i.OutcomeErrorCode = ""
+}
+
+// isRedirectURI - true if the URL is the redirect_uri the flow waits to be redirected to
+func (i *FlowInstance) isRedirectURI(u *url.URL) bool {
+	return NewRedirectMatcher(i.ProvidedRedirectURL).Match(u)
+}
+
+func isRedirectStatus(status int) bool {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package oauth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNativeAuthorizationRequest(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "KOAuth", r.UserAgent())
+		http.Redirect(w, r, "/continue", http.StatusFound)
+	})
+	mux.HandleFunc("/continue", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://client.example.com/cb?code=abc", http.StatusFound)
+	})
+	mux.HandleFunc("/error", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+	})
+	mux.HandleFunc("/consent", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<form method=post><button>Allow</button></form>"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	flow := func(path string) *FlowInstance {
+		authURL, _ := url.Parse(server.URL + path)
+		redirectURL, _ := url.Parse("https://client.example.com/cb")
+		return &FlowInstance{
+			Ctx:                 context.Background(),
+			AuthorizationURL:    authURL,
+			ProvidedRedirectURL: redirectURL,
+			RedirectedToURL:     new(url.URL),
+		}
+	}
+	client := nativeClient(nil, "", 0)
+
+	fi := flow("/authorize")
+	assert.Nil(t, fi.nativeAuthorizationRequest(client, "KOAuth"))
+	assert.Equal(t, "https://client.example.com/cb?code=abc", fi.RedirectedToURL.String())
+	assert.Equal(t, OutcomeRedirectedWithToken, fi.Outcome)
+	assert.Len(t, fi.RedirectChain(), 3)
+	assert.Equal(t, int64(http.StatusFound), fi.RedirectChain()[0].Status)
+
+	fi = flow("/error")
+	assert.Nil(t, fi.nativeAuthorizationRequest(client, "KOAuth"))
+	assert.Equal(t, "", fi.RedirectedToURL.String())
+	assert.Equal(t, OutcomeErrorPage, fi.Outcome)
+
+	fi = flow("/consent")
+	assert.Equal(t, errNeedsBrowser, fi.nativeAuthorizationRequest(client, "KOAuth"))
+}
+
+func TestNativeAllowed(t *testing.T) {
+	authURL, _ := url.Parse("https://idp.example.com/authorize?client_id=client")
+	fi := &FlowInstance{AuthorizationURL: authURL}
+	assert.False(t, fi.nativeAllowed())
+	fi.Options.Native = true
+	assert.True(t, fi.nativeAllowed())
+
+	// single use request objects and request_uri values are only sent by the browser
+	for _, p := range []string{RequestParam, RequestURIParam} {
+		fi.AuthorizationURL, _ = url.Parse("https://idp.example.com/authorize?client_id=client&" + p + "=x")
+		assert.False(t, fi.nativeAllowed())
+	}
+}
@@ -19,6 +19,22 @@ const (
 	PKCECodeVerifierParam        = "code_verifier"
 	PKCECodeChallengeParam       = "code_challenge"
 	PKCECodeChallengeMethodParam = "code_challenge_method"
+	DeviceCodeParam              = "device_code"
+	UserCodeParam                = "user_code"
+	IssuerParam                  = "iss"
+	ResponseModeParam            = "response_mode"
+	ResponseParam                = "response"    // JWT containing a JARM authorization response
+	RequestURIParam              = "request_uri" // references pushed or JWT-secured request parameters
+	RequestParam                 = "request"     // request object as defined in RFC 9101
+)
+
+// Grant types used in token requests
+const (
+	AuthorizationCodeGrantType = "authorization_code"
+	DeviceCodeGrantType        = "urn:ietf:params:oauth:grant-type:device_code"
+	ClientCredentialsGrantType = "client_credentials"
+	PasswordGrantType          = "password"
+	RefreshTokenGrantType      = "refresh_token"
 )
 
 // PKCE code challenge method param values
@@ -31,4 +47,10 @@ const (
 const (
 	FlowAuthorizationCode = "authorization-code"
 	FlowImplicit          = "implicit"
+	FlowDevice            = "device"
+	FlowResource          = "resource"
+	FlowRevoke            = "revoke"
+	FlowIntrospect        = "introspect"
+	FlowClientCredentials = "client-credentials"
+	FlowPassword          = "password"
 )
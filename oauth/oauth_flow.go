@@ -4,11 +4,13 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/base64"
+	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
-	"time"
+	"sync"
 
 	"github.com/chromedp/chromedp"
 	"github.com/morganc3/KOAuth/browser"
@@ -28,14 +30,66 @@ const (
 
 // FlowInstance - Represents an instance of an OAuth 2.0 Flow
 type FlowInstance struct {
-	FlowType            FlowType           `json:"-"`
-	FlowTimeoutSeconds  time.Duration      `json:"-"`
-	Ctx                 context.Context    `json:"-"`
-	Cancel              context.CancelFunc `json:"-"`
-	AuthorizationURL    *url.URL           `json:"-"`
-	ProvidedRedirectURL *url.URL           `json:"-"`
-	RedirectedToURL     *url.URL           `json:"-"`
-	ExchangeRequest     *ExchangeRequest   `json:"exchangeRequest,omitempty"`
+	FlowType FlowType `json:"-"`
+	Options  Options  `json:"-"`
+
+	// Logger of the flow, with the check and step it is run by. If nil, slog's default logger is used
+	Logger *slog.Logger `json:"-"`
+
+	// Stop waiting for the redirect once the tab meets this condition
+	WaitFor             *browser.WaitCondition `json:"-"`
+	Ctx                 context.Context        `json:"-"`
+	Cancel              context.CancelFunc     `json:"-"`
+	AuthorizationURL    *url.URL               `json:"-"`
+	ProvidedRedirectURL *url.URL               `json:"-"`
+	RedirectedToURL     *url.URL               `json:"-"`
+	LastURL             *url.URL               `json:"-"` // page loaded when the flow timed out
+	ExchangeRequest     *ExchangeRequest       `json:"exchangeRequest,omitempty"`
+	Token               *oauth2.Token          `json:"-"`
+
+	// Client used for this flow, if nil the client from the oauth config is used
+	Client *oauth2.Config `json:"-"`
+
+	// Key used for DPoP proofs, if nil no proofs are sent
+	DPoPKey       *SigningKey `json:"-"`
+	DPoPOptions   DPoPOptions `json:"-"`
+	DPoPJTI       string      `json:"-"` // jti of the last DPoP proof
+	DPoPLastProof string      `json:"-"` // last DPoP proof sent
+
+	// Device authorization request and response, only used by the device flow
+	DeviceAuthorizationRequest *ExchangeRequest     `json:"deviceAuthorizationRequest,omitempty"`
+	DeviceAuthorization        *DeviceAuthorization `json:"deviceAuthorization,omitempty"`
+
+	// Pushed authorization request and response, only set if parameters were pushed
+	PushedAuthorizationRequest *ExchangeRequest     `json:"pushedAuthorizationRequest,omitempty"`
+	PushedAuthorization        *PushedAuthorization `json:"pushedAuthorization,omitempty"`
+
+	// If the flow loaded pages in its tab, and the listeners of the tab
+	browserUsed      bool
+	documentStatuses *browser.DocumentStatuses
+	redirectChain    *browser.RedirectChain // recording while the flow runs
+	redirectHops     []browser.Hop          // recorded once the flow stopped
+
+	// Documents requested by a native authorization request, made without the browser
+	nativeHops []browser.Hop
+
+	// Parameters of the authorization response and how they were delivered, such as
+	// "query" or "form_post". For JARM responses, the parameters are the JWT's claims
+	AuthorizationResponse url.Values    `json:"authorizationResponse,omitempty"`
+	ResponseMode          string        `json:"responseMode,omitempty"`
+	JARM                  *JARMResponse `json:"jarm,omitempty"`
+
+	// Outcome of the flow, and the error code for error outcomes
+	Outcome          Outcome `json:"outcome,omitempty"`
+	OutcomeErrorCode string  `json:"outcomeErrorCode,omitempty"`
+
+	// Requests made to protected resources with the access token
+	ResourceRequests []*ExchangeRequest `json:"resourceRequests,omitempty"`
+
+	// Token revocation and introspection requests and responses
+	RevocationRequest    *ExchangeRequest       `json:"revocationRequest,omitempty"`
+	IntrospectionRequest *ExchangeRequest       `json:"introspectionRequest,omitempty"`
+	Introspection        map[string]interface{} `json:"introspection,omitempty"`
 }
 
 // ExchangeRequest - Represents an authorization code exchange request for an Access Token
@@ -57,15 +111,15 @@ type ExchangeRequest struct {
 // Scenario A is accounted for currently, as we use the same
 // chrome context for each check.
 
-// NewInstance - Creates a new OAuth flow instance
-func NewInstance(cx context.Context, cancel context.CancelFunc, ft FlowType, promptFlag string) *FlowInstance {
+// NewInstance - Creates a new OAuth flow instance with the given options
+func NewInstance(cx context.Context, cancel context.CancelFunc, ft FlowType, promptFlag string, opts Options) *FlowInstance {
 	redirectURI, err := url.Parse(config.OAuthConfig.OAuth2Config.RedirectURL)
 	if err != nil {
 		log.Fatalf("Failed to parse provided redirect_uri in config file")
 	}
 	flowInstance := FlowInstance{
 		FlowType:            ft,
-		FlowTimeoutSeconds:  time.Duration(config.GetOptAsInt(config.FlagTimeout)),
+		Options:             opts,
 		ProvidedRedirectURL: redirectURI,
 		RedirectedToURL:     new(url.URL),
 		Ctx:                 cx,
@@ -78,8 +132,19 @@ func NewInstance(cx context.Context, cancel context.CancelFunc, ft FlowType, pro
 
 // DoAuthorizationRequest - Perform OAuth 2.0 authorization request
 // based on the values from the oauth config.
-// Waits for redirect to expected redirect URL
+// Waits for redirect to expected redirect URL. With --native, the request
+// is made without the browser unless a page must be loaded in it
 func (i *FlowInstance) DoAuthorizationRequest() error {
+	logger := i.logger()
+	logger.Debug("Authorization request", "url", i.AuthorizationURL.String())
+	defer func() {
+		logger.Debug("Authorization request ended", "outcome", i.OutcomeString())
+	}()
+
+	if done, err := i.tryNativeAuthorizationRequest(); done {
+		return err
+	}
+
 	var actions []chromedp.Action
 
 	urlString := i.AuthorizationURL.String()
@@ -87,45 +152,166 @@ func (i *FlowInstance) DoAuthorizationRequest() error {
 	actions = append(actions, chromedp.Navigate(urlString))
 	// adds listener which will cancel the context
 	// if a redirect to redirect_uri occurs
-	ch := browser.WaitRedirect(i.Ctx, i.ProvidedRedirectURL.Host, i.ProvidedRedirectURL.Path)
-	c, err := browser.RunWithTimeOut(&i.Ctx, time.Duration(config.GetOptAsInt(config.FlagTimeout)), actions)
+	ch := browser.WaitRedirect(i.Ctx, NewRedirectMatcher(i.ProvidedRedirectURL))
+	// nil unless the response is posted with the web_message response mode, which never receives
+	var messages <-chan *browser.WebMessage
+	if GetQueryParameterFirst(i.AuthorizationURL, ResponseModeParam) == ResponseModeWebMessage {
+		var err error
+		messages, err = browser.WaitWebMessage(i.Ctx, func(m *browser.WebMessage) bool {
+			_, ok := webMessageResponse(m)
+			return ok
+		})
+		if err != nil {
+			i.setNotRedirectedOutcome(err)
+			return err
+		}
+	}
+	// handles consent or account chooser pages before the redirect
+	i.watchTab()
+	defer i.stopRedirectChain()
+	c, cancel, err := browser.RunWithTimeOut(&i.Ctx, i.Options.Timeout, actions)
+	defer cancel()
 	if err != nil {
+		i.LastURL = browser.CurrentURL(i.Ctx)
+		i.setNotRedirectedOutcome(err)
 		return err
 	}
 
+	// nil unless the step waits for a condition, which never receives
+	var waited <-chan struct{}
+	if i.WaitFor != nil {
+		waited = browser.WaitFor(c, *i.WaitFor)
+	}
+
 	select {
 	case <-c.Done():
+		// page the flow ended on, to detect if the session was lost
+		i.LastURL = browser.CurrentURL(i.Ctx)
+		i.setNotRedirectedOutcome(c.Err())
 		return err
-	case urlstr := <-ch:
-		i.RedirectedToURL = urlstr
+	case <-waited:
+		// stopped waiting before the timeout, the outcome is determined from the page
+		i.LastURL = browser.CurrentURL(i.Ctx)
+		i.setNotRedirectedOutcome(nil)
+		return nil
+	case redirect := <-ch:
+		i.RedirectedToURL = redirect.URL
+		i.setRedirectOutcome(redirect.URL, redirect.Form)
 		err = i.GetURLError() // get error as defined in rfc6749
 		if err != nil {
 			return err
 		}
+	case m := <-messages:
+		params, _ := webMessageResponse(m)
+		i.LastURL = browser.CurrentURL(i.Ctx)
+		i.setWebMessageOutcome(params)
+		if code := params.Get(ErrorParam); code != "" {
+			return fmt.Errorf("%s: %s", code, params.Get("error_description"))
+		}
 	}
 
 	return err
 
 }
 
+// tabWatch - listeners added to a tab, shared by every flow run in it
+type tabWatch struct {
+	hookRuns         *browser.HookRuns
+	documentStatuses *browser.DocumentStatuses
+	redirectChain    *browser.RedirectChain
+}
+
+// listeners of each tab, keyed by the tab's chromedp context. The tab's Target
+// can not be the key, as it is only set once the tab has run its first action
+var (
+	tabWatchesMu sync.Mutex
+	tabWatches   = make(map[*chromedp.Context]*tabWatch)
+)
+
+// run browser automation hooks and record document status codes and
+// the redirect chain in this flow's tab. Listeners can not be removed from a tab,
+// so they are only added by the first flow run in it, until its context is done
+func (i *FlowInstance) watchTab() {
+	i.browserUsed = true
+	tab := chromedp.FromContext(i.Ctx)
+
+	tabWatchesMu.Lock()
+	defer tabWatchesMu.Unlock()
+	w, ok := tabWatches[tab]
+	if !ok {
+		browser.LogNavigations(i.Ctx, i.logger())
+		w = &tabWatch{
+			hookRuns:         browser.WatchHooks(i.Ctx, i.Options.Timeout),
+			documentStatuses: browser.WatchDocumentStatuses(i.Ctx),
+			redirectChain:    browser.WatchRedirectChain(i.Ctx),
+		}
+		tabWatches[tab] = w
+		// the listeners are removed along with the context they were added with
+		context.AfterFunc(i.Ctx, func() {
+			tabWatchesMu.Lock()
+			defer tabWatchesMu.Unlock()
+			if tabWatches[tab] == w {
+				delete(tabWatches, tab)
+			}
+		})
+	}
+	// every flow may run hooks up to the maximum
+	w.hookRuns.Reset()
+	i.documentStatuses = w.documentStatuses
+	i.redirectChain = w.redirectChain
+	i.redirectChain.Restart()
+}
+
+// stops recording the redirect chain and keeps this flow's hops, as
+// the tab's redirect chain is restarted by the next flow run in it
+func (i *FlowInstance) stopRedirectChain() {
+	i.redirectChain.Stop()
+	i.redirectHops = i.redirectChain.Hops()
+	i.redirectChain = nil
+}
+
+func (i *FlowInstance) logger() *slog.Logger {
+	if i.Logger == nil {
+		return slog.Default()
+	}
+	return i.Logger
+}
+
+// BrowserUsed - true if the flow loaded pages in its tab
+func (i *FlowInstance) BrowserUsed() bool {
+	return i.browserUsed
+}
+
+// RedirectChain - documents loaded during the flow's authorization request,
+// in the order they were requested
+func (i *FlowInstance) RedirectChain() []browser.Hop {
+	if i.nativeHops != nil {
+		return i.nativeHops
+	}
+	if i.redirectChain == nil {
+		return i.redirectHops
+	}
+	return i.redirectChain.Hops()
+}
+
 // Exchange - uses forked version of oauth2 package
 // Same as Exchange() from https://github.com/golang/oauth2 but
 // takes arbitrary url values and gives access to HTTP request and response
 func (i *FlowInstance) Exchange(ctx context.Context, v url.Values) (*oauth2.Token, error) {
-	req, resp, tkn, err := oauth2.RetrieveToken(ctx, &config.OAuthConfig.OAuth2Config, v)
+	req, resp, tkn, err := oauth2.RetrieveToken(i.withDPoP(ctx), i.ClientConfig(), v)
 	var reqString, respString string
 	if req != nil {
 		reqBytes, err := httputil.DumpRequest(req, true)
 		reqString = string(reqBytes)
 		if err != nil {
-			log.Println(err)
+			i.logger().Warn("Could not dump token request", "err", err)
 		}
 	}
 	if resp != nil {
 		respBytes, err := httputil.DumpResponse(resp, true)
 		respString = string(respBytes)
 		if err != nil {
-			log.Println(err)
+			i.logger().Warn("Could not dump token response", "err", err)
 		}
 	}
 
@@ -135,10 +321,30 @@ func (i *FlowInstance) Exchange(ctx context.Context, v url.Values) (*oauth2.Toke
 		RequestString:  reqString,
 		ResponseString: respString,
 	}
+	i.Token = tkn
+	if err != nil {
+		i.setExchangeErrorOutcome(err)
+	}
 	return tkn, err
 
 }
 
+// ClientConfig - oauth2 config of the client used for this flow
+func (i *FlowInstance) ClientConfig() *oauth2.Config {
+	if i.Client != nil {
+		return i.Client
+	}
+	return &config.OAuthConfig.OAuth2Config
+}
+
+// UseClient - use a different client for this flow, updating the client_id
+// of the Authorization URL. Client authentication in back channel requests
+// will use the credentials of this client
+func (i *FlowInstance) UseClient(conf *oauth2.Config) {
+	i.Client = conf
+	SetQueryParameter(i.AuthorizationURL, ClientIDParam, conf.ClientID)
+}
+
 // GenerateAuthorizationURL - generates oauth2 authorization url based on config values
 func GenerateAuthorizationURL(flowType FlowType, state, promptFlag string) *url.URL {
 	var option oauth2.AuthCodeOption = oauth2.SetAuthURLParam(ResponseTypeParam, string(flowType))
@@ -154,6 +360,10 @@ func GenerateAuthorizationURL(flowType FlowType, state, promptFlag string) *url.
 		SetQueryParameter(URL, "prompt", promptFlag)
 	}
 
+	if config.OAuthConfig.ResponseMode != "" {
+		SetQueryParameter(URL, ResponseModeParam, config.OAuthConfig.ResponseMode)
+	}
+
 	// some authz servers (such as Okta) require a Nonce
 	// despite it not being part of the RFC
 	SetQueryParameter(URL, "nonce", randStr(32))
@@ -187,6 +397,11 @@ func GetImplicitAccessTokenFromURL(urlString string) string {
 	return tokenString
 }
 
+// RandomString - random URL safe string of the provided length
+func RandomString(len int) string {
+	return randStr(len)
+}
+
 func randStr(len int) string {
 	buff := make([]byte, len)
 	rand.Read(buff)
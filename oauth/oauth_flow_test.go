@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/chromedp/chromedp"
 	"github.com/stretchr/testify/assert"
@@ -11,7 +12,7 @@ import (
 
 func TestURLFunctions(t *testing.T) {
 	ctx, cancel := chromedp.NewContext(context.Background())
-	flow := NewInstance(ctx, cancel, ImplicitFlowResponseType, "none")
+	flow := NewInstance(ctx, cancel, ImplicitFlowResponseType, "none", Options{})
 	flow.AuthorizationURL, _ = url.Parse("http://example.com")
 	AddQueryParameter(flow.AuthorizationURL, "k1", "v1")
 	assert.Equal(t, "http://example.com?k1=v1", flow.AuthorizationURL.String())
@@ -30,3 +31,31 @@ func TestURLFunctions(t *testing.T) {
 	assert.Equal(t, "http://example.com?k1=newvalue", flow.AuthorizationURL.String())
 
 }
+
+func TestWatchTab(t *testing.T) {
+	ctx, cancel := chromedp.NewContext(context.Background())
+	first := NewInstance(ctx, cancel, AuthorizationCodeFlowResponseType, "none", Options{})
+	second := NewInstance(ctx, cancel, AuthorizationCodeFlowResponseType, "none", Options{})
+	assert.False(t, first.BrowserUsed())
+
+	// flows sharing a tab share its listeners, rather than each adding their own
+	first.watchTab()
+	first.stopRedirectChain()
+	second.watchTab()
+	assert.True(t, first.BrowserUsed())
+	assert.Same(t, first.documentStatuses, second.documentStatuses)
+	assert.Len(t, tabWatches, 1)
+
+	otherCtx, otherCancel := chromedp.NewContext(ctx)
+	other := NewInstance(otherCtx, otherCancel, AuthorizationCodeFlowResponseType, "none", Options{})
+	other.watchTab()
+	assert.False(t, first.documentStatuses == other.documentStatuses)
+
+	// closing a tab forgets its listeners, along with those of the tabs created from it
+	cancel()
+	assert.Eventually(t, func() bool {
+		tabWatchesMu.Lock()
+		defer tabWatchesMu.Unlock()
+		return len(tabWatches) == 0
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,15 @@
+package oauth
+
+import "time"
+
+// Options - settings of flows provided on the command line. The caller passes
+// them to NewInstance, so that flows do not read command line flags themselves
+type Options struct {
+	// how long a flow waits to be redirected to the redirect_uri
+	Timeout time.Duration
+	// perform authorization requests with an HTTP client when possible
+	Native bool
+	// proxy and User-Agent of native authorization requests
+	Proxy     string
+	UserAgent string
+}
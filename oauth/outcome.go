@@ -0,0 +1,129 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// Outcome - what happened at the end of a flow
+type Outcome string
+
+// Outcomes of flows, as defined in provided JSON check structure
+const (
+	// redirected to the redirect_uri with an authorization code or access token
+	OutcomeRedirectedWithToken Outcome = "redirected-with-token"
+	// redirected to the redirect_uri with an error
+	OutcomeRedirectedWithError Outcome = "redirected-with-error"
+	// redirected to the redirect_uri without a token or error
+	OutcomeRedirected Outcome = "redirected"
+	// never redirected, an error page was shown at the authorization server
+	OutcomeErrorPage Outcome = "error-page"
+	// never redirected, and no error page was shown
+	OutcomeTimeout Outcome = "timeout"
+	// the browser failed to load the authorization URL
+	OutcomeNavigationError Outcome = "navigation-error"
+	// the token endpoint responded with an error
+	OutcomeExchangeError Outcome = "exchange-error"
+)
+
+// Error codes that can be returned because of consent, login or account
+// selection rather than the request being invalid
+var interactionErrors = []string{
+	AccessDeniedError,
+	LoginRequiredError,
+	InteractionRequiredError,
+	"consent_required",
+	"account_selection_required",
+}
+
+// OutcomeString - outcome of the flow, followed by the
+// error code for error outcomes, such as "redirected-with-error:access_denied"
+func (i *FlowInstance) OutcomeString() string {
+	if i.OutcomeErrorCode == "" {
+		return string(i.Outcome)
+	}
+	return string(i.Outcome) + ":" + i.OutcomeErrorCode
+}
+
+// OutcomeMatches - true if the outcome of the flow matches the expected outcome. An
+// expected error outcome without an error code matches any error code
+func (i *FlowInstance) OutcomeMatches(expected string) bool {
+	if expected == i.OutcomeString() {
+		return true
+	}
+	return !strings.Contains(expected, ":") && expected == string(i.Outcome)
+}
+
+// InteractionError - true if the flow was rejected with an error
+// which may be caused by consent, login or account selection
+func (i *FlowInstance) InteractionError() bool {
+	if i.Outcome != OutcomeRedirectedWithError {
+		return false
+	}
+	for _, code := range interactionErrors {
+		if code == i.OutcomeErrorCode {
+			return true
+		}
+	}
+	return false
+}
+
+// sets the authorization response and outcome of an authorization request from
+// the URL we were redirected to, and the body of a form_post response
+func (i *FlowInstance) setRedirectOutcome(redirectedTo *url.URL, form url.Values) {
+	i.setAuthorizationResponse(redirectedTo, form)
+	i.setResponseOutcome()
+}
+
+// sets the authorization response and outcome of an authorization request from
+// a message posted with the web_message response mode. As the response reached
+// the client, the outcome is the same as if the flow was redirected with it
+func (i *FlowInstance) setWebMessageOutcome(params url.Values) {
+	i.setWebMessageResponse(params)
+	i.setResponseOutcome()
+}
+
+// sets the outcome of an authorization request from its authorization response
+func (i *FlowInstance) setResponseOutcome() {
+	params := i.AuthorizationResponse
+
+	switch {
+	case params.Get(ErrorParam) != "":
+		i.Outcome = OutcomeRedirectedWithError
+		i.OutcomeErrorCode = params.Get(ErrorParam)
+	case params.Get(AuthorizationCodeFlowResponseType) != "" || params.Get(AccessTokenParam) != "" ||
+		params.Get("id_token") != "":
+		i.Outcome = OutcomeRedirectedWithToken
+	default:
+		i.Outcome = OutcomeRedirected
+	}
+}
+
+// sets the outcome of an authorization request which was never redirected. An error
+// page is a page responding with an error status code, or with "error" in its URL
+func (i *FlowInstance) setNotRedirectedOutcome(runErr error) {
+	if runErr != nil && !errors.Is(runErr, context.DeadlineExceeded) {
+		i.Outcome = OutcomeNavigationError
+		return
+	}
+
+	i.Outcome = OutcomeTimeout
+	if i.LastURL == nil || i.LastURL.Host == "" {
+		return
+	}
+	status := int64(0)
+	if i.documentStatuses != nil {
+		status = i.documentStatuses.Status(i.LastURL.String())
+	}
+	if status >= 400 || strings.Contains(strings.ToLower(i.LastURL.String()), "error") {
+		i.Outcome = OutcomeErrorPage
+	}
+}
+
+// sets the outcome of a failed token request
+func (i *FlowInstance) setExchangeErrorOutcome(err error) {
+	i.Outcome = OutcomeExchangeError
+	i.OutcomeErrorCode = GetTokenErrorCode(err)
+}
@@ -0,0 +1,30 @@
+package oauth
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutcome(t *testing.T) {
+	var i FlowInstance
+	u, _ := url.Parse("https://example.com/cb?error=access_denied&state=x")
+	i.setRedirectOutcome(u, nil)
+	assert.Equal(t, "redirected-with-error:access_denied", i.OutcomeString())
+	assert.True(t, i.OutcomeMatches("redirected-with-error"))
+	assert.True(t, i.OutcomeMatches("redirected-with-error:access_denied"))
+	assert.False(t, i.OutcomeMatches("redirected-with-error:invalid_request"))
+	assert.True(t, i.InteractionError())
+
+	i = FlowInstance{}
+	u, _ = url.Parse("https://example.com/cb#access_token=abc&state=x")
+	i.setRedirectOutcome(u, nil)
+	assert.Equal(t, "redirected-with-token", i.OutcomeString())
+	assert.False(t, i.InteractionError())
+
+	i = FlowInstance{}
+	i.LastURL, _ = url.Parse("https://as.example.com/oauth/error?code=1")
+	i.setNotRedirectedOutcome(nil)
+	assert.Equal(t, "error-page", i.OutcomeString())
+}
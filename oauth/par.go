@@ -0,0 +1,59 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/morganc3/KOAuth/config"
+)
+
+// PushedAuthorization - Represents a pushed authorization response as defined in RFC 9126
+type PushedAuthorization struct {
+	RequestURI string `json:"request_uri"`
+	ExpiresIn  int    `json:"expires_in"`
+}
+
+// PushAuthorizationRequest - POSTs the parameters of the Authorization URL to the
+// pushed authorization request endpoint. On success, the Authorization URL is
+// replaced with one containing only the client_id and the returned request_uri
+func (i *FlowInstance) PushAuthorizationRequest(ctx context.Context) error {
+	endpoint := config.OAuthConfig.PARURL
+	if endpoint == "" {
+		return errors.New("No par_url provided in config file")
+	}
+
+	conf := i.ClientConfig()
+	req, err := newClientAuthRequest(ctx, conf, endpoint, i.AuthorizationURL.Query())
+	if err != nil {
+		return err
+	}
+	er, body, err := doBackChannelRequest(nil, req)
+	i.PushedAuthorizationRequest = er
+	if err != nil {
+		return err
+	}
+	// RFC 9126 requires 201, but some servers respond with 200
+	if er.Response.StatusCode != http.StatusCreated && er.Response.StatusCode != http.StatusOK {
+		return fmt.Errorf("Pushed authorization request failed: %s %s", er.Response.Status, getErrorCodeFromBody(body))
+	}
+
+	var pa PushedAuthorization
+	err = json.Unmarshal(body, &pa)
+	if err != nil {
+		return err
+	}
+	if pa.RequestURI == "" {
+		return errors.New("Pushed authorization response is missing request_uri")
+	}
+	i.PushedAuthorization = &pa
+
+	q := url.Values{}
+	q.Set(ClientIDParam, conf.ClientID)
+	q.Set(RequestURIParam, pa.RequestURI)
+	i.AuthorizationURL.RawQuery = q.Encode()
+	return nil
+}
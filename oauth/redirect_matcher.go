@@ -0,0 +1,92 @@
+package oauth
+
+import (
+	"net"
+	"net/url"
+	"strings"
+)
+
+// Kinds of redirect URIs, as described in RFC 8252
+const (
+	RedirectURIWeb          = "web"           // https, or http with a host which is not a loopback address
+	RedirectURILoopback     = "loopback"      // http with a loopback IP literal or localhost
+	RedirectURICustomScheme = "custom-scheme" // private-use URI scheme, such as com.example.app:/callback
+)
+
+// RedirectMatcher - matches URLs against an expected redirect URI. Schemes and hosts are
+// compared case-insensitively and default ports are ignored. Loopback redirect URIs match
+// any port, as the port is chosen by native apps when the request is made (RFC 8252 7.3).
+// Parameters in the query of the redirect URI must be present in matched URLs, which
+// can contain any other parameters, such as those of the authorization response
+type RedirectMatcher struct {
+	redirectURI *url.URL
+	anyPort     bool
+}
+
+// NewRedirectMatcher - matcher for URLs the redirect URI redirects to
+func NewRedirectMatcher(redirectURI *url.URL) *RedirectMatcher {
+	return &RedirectMatcher{
+		redirectURI: redirectURI,
+		anyPort:     RedirectURIKind(redirectURI) == RedirectURILoopback,
+	}
+}
+
+// Match - true if the URL is the redirect URI
+func (m *RedirectMatcher) Match(u *url.URL) bool {
+	r := m.redirectURI
+	if !strings.EqualFold(u.Scheme, r.Scheme) || u.Opaque != r.Opaque || u.Path != r.Path {
+		return false
+	}
+	if !strings.EqualFold(u.Hostname(), r.Hostname()) {
+		return false
+	}
+	if !m.anyPort && portOrDefault(u) != portOrDefault(r) {
+		return false
+	}
+
+	query := u.Query()
+	for key, values := range r.Query() {
+		for _, v := range values {
+			if !sliceContainsString(query[key], v) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// RedirectURIKind - whether the redirect URI is a web, loopback or custom scheme redirect URI
+func RedirectURIKind(u *url.URL) string {
+	switch strings.ToLower(u.Scheme) {
+	case "https":
+		return RedirectURIWeb
+	case "http":
+		if isLoopback(u.Hostname()) {
+			return RedirectURILoopback
+		}
+		return RedirectURIWeb
+	}
+	return RedirectURICustomScheme
+}
+
+// localhost is not recommended for loopback redirect URIs, but is commonly used by native apps
+func isLoopback(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func portOrDefault(u *url.URL) string {
+	if p := u.Port(); p != "" {
+		return p
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http":
+		return "80"
+	case "https":
+		return "443"
+	}
+	return ""
+}
@@ -0,0 +1,46 @@
+package oauth
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedirectMatcher(t *testing.T) {
+	match := func(redirectURI, u string) bool {
+		r, _ := url.Parse(redirectURI)
+		p, _ := url.Parse(u)
+		return NewRedirectMatcher(r).Match(p)
+	}
+
+	assert.True(t, match("https://example.com/cb", "https://EXAMPLE.com:443/cb?code=abc"))
+	assert.False(t, match("https://example.com/cb", "https://example.com:8443/cb?code=abc"))
+	assert.False(t, match("https://example.com/cb", "https://example.com/cb/other?code=abc"))
+
+	// loopback redirect URIs match any port
+	assert.True(t, match("http://127.0.0.1:8080/cb", "http://127.0.0.1:51789/cb?code=abc"))
+	assert.True(t, match("http://[::1]/cb", "http://[::1]:51789/cb?code=abc"))
+	assert.False(t, match("http://127.0.0.1:8080/cb", "http://127.0.0.2:8080/cb?code=abc"))
+
+	assert.True(t, match("com.example.app:/callback", "com.example.app:/callback?code=abc"))
+	assert.False(t, match("com.example.app:/callback", "com.example.app.attacker:/callback?code=abc"))
+	assert.True(t, match("com.example.app:callback", "com.example.app:callback?code=abc"))
+
+	// query components of the redirect URI must be kept
+	assert.True(t, match("https://example.com/cb?tenant=a", "https://example.com/cb?code=abc&tenant=a"))
+	assert.False(t, match("https://example.com/cb?tenant=a", "https://example.com/cb?code=abc&tenant=b"))
+	assert.False(t, match("https://example.com/cb?tenant=a", "https://example.com/cb?code=abc"))
+}
+
+func TestRedirectURIKind(t *testing.T) {
+	kind := func(s string) string {
+		u, _ := url.Parse(s)
+		return RedirectURIKind(u)
+	}
+	assert.Equal(t, RedirectURIWeb, kind("https://127.0.0.1/cb"))
+	assert.Equal(t, RedirectURIWeb, kind("http://example.com/cb"))
+	assert.Equal(t, RedirectURILoopback, kind("http://127.0.0.1:8080/cb"))
+	assert.Equal(t, RedirectURILoopback, kind("http://localhost/cb"))
+	assert.Equal(t, RedirectURICustomScheme, kind("com.example.app:/callback"))
+}
@@ -0,0 +1,125 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Where the access token is sent in protected resource requests, as defined in RFC 6750
+const (
+	TokenInHeader = "header"
+	TokenInQuery  = "query"
+	TokenInBody   = "body"
+)
+
+// ResourceOptions - options for a request to a protected resource
+type ResourceOptions struct {
+	URL       string `json:"url,omitempty"`
+	Method    string `json:"method,omitempty"`
+	Token     string `json:"token,omitempty"`
+	TokenType string `json:"tokenType,omitempty"` // "Bearer" or "DPoP"
+	TokenIn   string `json:"tokenIn,omitempty"`   // header, query or body
+}
+
+// RequestResource - calls a protected resource with an access token. When the token
+// type is DPoP, a proof containing the hash of the token is sent alongside it, and
+// the request is retried once if the resource requires a nonce
+func (i *FlowInstance) RequestResource(ctx context.Context, opts ResourceOptions) (*ExchangeRequest, error) {
+	if opts.URL == "" {
+		return nil, errors.New("No resource URL provided")
+	}
+	if opts.TokenType == "" {
+		opts.TokenType = "Bearer"
+	}
+	dpop := strings.EqualFold(opts.TokenType, DPoPTokenType)
+	if dpop && i.DPoPKey == nil {
+		return nil, errors.New("DPoP token type requires DPoP to be enabled")
+	}
+
+	req, err := newResourceRequest(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	if dpop {
+		err = i.addResourceDPoPProof(req, opts.Token, "")
+		if err != nil {
+			return nil, err
+		}
+	}
+	er, _, err := doBackChannelRequest(nil, req)
+	i.ResourceRequests = append(i.ResourceRequests, er)
+	if err != nil {
+		return er, err
+	}
+
+	nonce := er.Response.Header.Get(DPoPNonceHeader)
+	if !dpop || nonce == "" || er.Response.StatusCode != http.StatusUnauthorized {
+		return er, nil
+	}
+	req, err = newResourceRequest(ctx, opts)
+	if err != nil {
+		return er, err
+	}
+	err = i.addResourceDPoPProof(req, opts.Token, nonce)
+	if err != nil {
+		return er, err
+	}
+	er, _, err = doBackChannelRequest(nil, req)
+	i.ResourceRequests = append(i.ResourceRequests, er)
+	return er, err
+}
+
+// ResourceAccepted - true if the resource responded with a 2xx status code
+func (er *ExchangeRequest) ResourceAccepted() bool {
+	return er != nil && er.Response != nil &&
+		er.Response.StatusCode >= 200 && er.Response.StatusCode < 300
+}
+
+func newResourceRequest(ctx context.Context, opts ResourceOptions) (*http.Request, error) {
+	u, err := url.Parse(opts.URL)
+	if err != nil {
+		return nil, err
+	}
+	method := opts.Method
+	if method == "" {
+		method = http.MethodGet
+		if opts.TokenIn == TokenInBody {
+			method = http.MethodPost
+		}
+	}
+
+	var body string
+	switch opts.TokenIn {
+	case TokenInQuery:
+		q := u.Query()
+		q.Set(AccessTokenParam, opts.Token)
+		u.RawQuery = q.Encode()
+	case TokenInBody:
+		body = url.Values{AccessTokenParam: {opts.Token}}.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	switch opts.TokenIn {
+	case TokenInBody:
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	case TokenInQuery:
+	default:
+		req.Header.Set("Authorization", opts.TokenType+" "+opts.Token)
+	}
+	return req, nil
+}
+
+func (i *FlowInstance) addResourceDPoPProof(req *http.Request, token, nonce string) error {
+	proof, err := i.DPoPProof(req.Method, req.URL, token, nonce)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(DPoPHeader, proof)
+	return nil
+}
@@ -0,0 +1,32 @@
+package oauth
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewResourceRequest(t *testing.T) {
+	opts := ResourceOptions{URL: "https://api.example.com/me?a=b", Token: "tok", TokenType: "Bearer"}
+
+	req, err := newResourceRequest(context.TODO(), opts)
+	assert.Nil(t, err)
+	assert.Equal(t, http.MethodGet, req.Method)
+	assert.Equal(t, "Bearer tok", req.Header.Get("Authorization"))
+
+	opts.TokenIn = TokenInQuery
+	req, err = newResourceRequest(context.TODO(), opts)
+	assert.Nil(t, err)
+	assert.Equal(t, "tok", req.URL.Query().Get(AccessTokenParam))
+	assert.Equal(t, "b", req.URL.Query().Get("a"))
+	assert.Equal(t, "", req.Header.Get("Authorization"))
+
+	opts.TokenIn = TokenInBody
+	req, err = newResourceRequest(context.TODO(), opts)
+	assert.Nil(t, err)
+	assert.Equal(t, http.MethodPost, req.Method)
+	assert.Nil(t, req.ParseForm())
+	assert.Equal(t, "tok", req.PostForm.Get(AccessTokenParam))
+}
@@ -0,0 +1,185 @@
+package oauth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/morganc3/KOAuth/browser"
+)
+
+// Response modes, the ways an authorization response can be delivered to the redirect_uri
+const (
+	ResponseModeQuery    = "query"
+	ResponseModeFragment = "fragment"
+	ResponseModeFormPost = "form_post"
+	// suffix of JWT Secured Authorization Response Modes (JARM), such as "query.jwt"
+	ResponseModeJWTSuffix = ".jwt"
+	// the response is posted with postMessage by a page of the authorization
+	// server, to the window which made the request, rather than sent to the redirect_uri
+	ResponseModeWebMessage = "web_message"
+)
+
+// "type" of messages containing an authorization response with the web_message response mode
+const webMessageResponseType = "authorization_response"
+
+// parameters which can only be part of an authorization response
+var responseParams = []string{
+	AuthorizationCodeFlowResponseType,
+	AccessTokenParam,
+	"id_token",
+	ErrorParam,
+	ResponseParam,
+}
+
+// JARMResponse - decoded JWT of a JWT Secured Authorization Response (JARM)
+type JARMResponse struct {
+	Header map[string]interface{} `json:"header"`
+	Claims map[string]interface{} `json:"claims"`
+}
+
+// ResponseParameter - first value of a parameter of the authorization response,
+// regardless of how the response was delivered
+func (i *FlowInstance) ResponseParameter(key string) string {
+	if i.AuthorizationResponse == nil && i.RedirectedToURL != nil {
+		_, params := urlResponse(i.RedirectedToURL)
+		return params.Get(key)
+	}
+	return i.AuthorizationResponse.Get(key)
+}
+
+// sets the parameters of the authorization response and how it was delivered, from
+// the URL we were redirected to and the body of a form_post response. The parameters
+// of JARM responses are the claims of their JWT, which is not verified
+func (i *FlowInstance) setAuthorizationResponse(redirectedTo *url.URL, form url.Values) {
+	if form != nil {
+		i.ResponseMode = ResponseModeFormPost
+		i.AuthorizationResponse = form
+	} else {
+		i.ResponseMode, i.AuthorizationResponse = urlResponse(redirectedTo)
+	}
+
+	jwt := i.AuthorizationResponse.Get(ResponseParam)
+	if jwt == "" {
+		return
+	}
+	header, err := DecodeJWTHeader(jwt)
+	if err != nil {
+		return
+	}
+	claims, err := DecodeJWTClaims(jwt)
+	if err != nil {
+		return
+	}
+	i.ResponseMode += ResponseModeJWTSuffix
+	i.JARM = &JARMResponse{Header: header, Claims: claims}
+	i.AuthorizationResponse = claimValues(claims)
+}
+
+// sets the parameters of an authorization response posted with the web_message response mode
+func (i *FlowInstance) setWebMessageResponse(params url.Values) {
+	i.ResponseMode = ResponseModeWebMessage
+	i.AuthorizationResponse = params
+}
+
+// parameters of the authorization response in a message posted with the web_message
+// response mode, such as {"type": "authorization_response", "response": {"code": "..."}}.
+// Returns false if the message is not an authorization response
+func webMessageResponse(m *browser.WebMessage) (url.Values, bool) {
+	var message struct {
+		Type     string                 `json:"type"`
+		Response map[string]interface{} `json:"response"`
+	}
+	if json.Unmarshal(m.Data, &message) != nil {
+		// some pages post the message as a JSON string
+		var s string
+		if json.Unmarshal(m.Data, &s) != nil || json.Unmarshal([]byte(s), &message) != nil {
+			return nil, false
+		}
+	}
+	if message.Type != webMessageResponseType || message.Response == nil {
+		return nil, false
+	}
+	return claimValues(message.Response), true
+}
+
+// response mode and parameters of an authorization response in the query or fragment
+func urlResponse(redirectedTo *url.URL) (string, url.Values) {
+	query := redirectedTo.Query()
+	fragment, _ := url.ParseQuery(redirectedTo.Fragment)
+	if hasResponseParams(fragment) || !hasResponseParams(query) && len(fragment) > 0 {
+		return ResponseModeFragment, fragment
+	}
+	return ResponseModeQuery, query
+}
+
+func hasResponseParams(v url.Values) bool {
+	for _, p := range responseParams {
+		if _, ok := v[p]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// claims of a JARM response as parameters
+func claimValues(claims map[string]interface{}) url.Values {
+	v := make(url.Values)
+	for key, value := range claims {
+		switch c := value.(type) {
+		case string:
+			v.Set(key, c)
+		case float64:
+			v.Set(key, strconv.FormatFloat(c, 'f', -1, 64))
+		case []interface{}: // such as an aud array
+			for _, e := range c {
+				v.Add(key, fmt.Sprint(e))
+			}
+		default:
+			v.Set(key, fmt.Sprint(c))
+		}
+	}
+	return v
+}
+
+// ValidateJARM - validates the claims of a JARM response as required by the JARM
+// specification. The signature is not verified, but must not use the "none" algorithm
+func (i *FlowInstance) ValidateJARM(issuer, clientID string) error {
+	if i.JARM == nil {
+		return errors.New("Authorization response was not a JARM response")
+	}
+	alg, _ := i.JARM.Header["alg"].(string)
+	if alg == "" || strings.EqualFold(alg, "none") {
+		return fmt.Errorf("JARM response is not signed, alg is %q", alg)
+	}
+
+	claims := i.AuthorizationResponse
+	if issuer != "" && claims.Get(IssuerParam) != issuer {
+		return fmt.Errorf("JARM response iss %q does not match the issuer %s", claims.Get(IssuerParam), issuer)
+	}
+	if !sliceContainsString(claims["aud"], clientID) {
+		return fmt.Errorf("JARM response aud %q does not contain the client_id %s", claims["aud"], clientID)
+	}
+
+	exp, ok := i.JARM.Claims["exp"].(float64)
+	if !ok {
+		return errors.New("JARM response does not contain an exp claim")
+	}
+	if time.Unix(int64(exp), 0).Before(time.Now()) {
+		return fmt.Errorf("JARM response expired at %s", time.Unix(int64(exp), 0))
+	}
+	return nil
+}
+
+func sliceContainsString(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package oauth
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/morganc3/KOAuth/browser"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthorizationResponse(t *testing.T) {
+	parse := func(s string) *url.URL {
+		u, _ := url.Parse(s)
+		return u
+	}
+
+	// redirect_uri with its own query, and the response in the fragment
+	i := FlowInstance{}
+	i.setRedirectOutcome(parse("https://example.com/cb?app=1#access_token=abc&state=s"), nil)
+	assert.Equal(t, ResponseModeFragment, i.ResponseMode)
+	assert.Equal(t, "abc", i.ResponseParameter(AccessTokenParam))
+
+	i = FlowInstance{}
+	i.setRedirectOutcome(parse("https://example.com/cb"), url.Values{"code": {"xyz"}})
+	assert.Equal(t, ResponseModeFormPost, i.ResponseMode)
+	assert.Equal(t, "xyz", i.ResponseParameter(AuthorizationCodeFlowResponseType))
+	assert.Equal(t, OutcomeRedirectedWithToken, i.Outcome)
+
+	key, err := GenerateSigningKey(AlgES256)
+	assert.Nil(t, err)
+	claims := map[string]interface{}{
+		"iss":  "https://as.example.com",
+		"aud":  "client",
+		"exp":  time.Now().Add(time.Minute).Unix(),
+		"code": "jarm-code",
+	}
+	jwt, err := SignJWT(key, nil, claims)
+	assert.Nil(t, err)
+
+	i = FlowInstance{}
+	i.setRedirectOutcome(parse("https://example.com/cb?response="+jwt), nil)
+	assert.Equal(t, "query.jwt", i.ResponseMode)
+	assert.Equal(t, "jarm-code", i.ResponseParameter(AuthorizationCodeFlowResponseType))
+	assert.Nil(t, i.ValidateJARM("https://as.example.com", "client"))
+	assert.NotNil(t, i.ValidateJARM("https://other.example.com", "client"))
+	assert.NotNil(t, i.ValidateJARM("https://as.example.com", "other-client"))
+
+	unsigned, err := SignJWT(&SigningKey{Alg: AlgNone}, nil, claims)
+	assert.Nil(t, err)
+	i = FlowInstance{}
+	i.setRedirectOutcome(parse("https://example.com/cb"), url.Values{"response": {unsigned}})
+	assert.Equal(t, "form_post.jwt", i.ResponseMode)
+	assert.NotNil(t, i.ValidateJARM("https://as.example.com", "client"))
+}
+
+func TestWebMessageResponse(t *testing.T) {
+	message := func(data string) *browser.WebMessage {
+		return &browser.WebMessage{Data: json.RawMessage(data), TargetOrigin: "https://client.example.com"}
+	}
+
+	params, ok := webMessageResponse(message(`{"type":"authorization_response","response":{"code":"abc","state":"xyz"}}`))
+	assert.True(t, ok)
+	assert.Equal(t, "abc", params.Get("code"))
+
+	// messages posted as a JSON string
+	params, ok = webMessageResponse(message(`"{\"type\":\"authorization_response\",\"response\":{\"error\":\"access_denied\"}}"`))
+	assert.True(t, ok)
+	i := FlowInstance{}
+	i.setWebMessageOutcome(params)
+	assert.Equal(t, ResponseModeWebMessage, i.ResponseMode)
+	assert.Equal(t, OutcomeRedirectedWithError, i.Outcome)
+	assert.Equal(t, "access_denied", i.OutcomeErrorCode)
+
+	// other messages posted by the page
+	for _, data := range []string{`"ready"`, `{"type":"resize","height":100}`, `42`} {
+		_, ok = webMessageResponse(message(data))
+		assert.False(t, ok)
+	}
+}
@@ -0,0 +1,99 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/morganc3/KOAuth/config"
+	"golang.org/x/oauth2"
+)
+
+// Parameters of revocation (RFC 7009) and introspection (RFC 7662) requests
+const (
+	TokenParam         = "token"
+	TokenTypeHintParam = "token_type_hint"
+	ActiveParam        = "active"
+)
+
+// TokenRequestOptions - the token sent to the revocation or introspection
+// endpoint, and whether the client authenticates the request
+type TokenRequestOptions struct {
+	Token         string
+	TokenTypeHint string
+	// send the request without any client credentials
+	Unauthenticated bool
+}
+
+// RevokeToken - revokes a token at the revocation endpoint. Returns an
+// error if the endpoint did not respond with a 200 status code
+func (i *FlowInstance) RevokeToken(ctx context.Context, opts TokenRequestOptions) error {
+	endpoint := config.OAuthConfig.RevocationURL
+	if endpoint == "" {
+		return errors.New("No revocation_url provided in config file")
+	}
+	er, body, err := i.tokenEndpointRequest(ctx, endpoint, opts)
+	i.RevocationRequest = er
+	if err != nil {
+		return err
+	}
+	if er.Response.StatusCode != http.StatusOK {
+		return tokenRequestError("Revocation", er.Response, body)
+	}
+	return nil
+}
+
+// IntrospectToken - sends a token to the introspection endpoint, returning
+// the introspection response
+func (i *FlowInstance) IntrospectToken(ctx context.Context, opts TokenRequestOptions) (map[string]interface{}, error) {
+	endpoint := config.OAuthConfig.IntrospectionURL
+	if endpoint == "" {
+		return nil, errors.New("No introspection_url provided in config file")
+	}
+	er, body, err := i.tokenEndpointRequest(ctx, endpoint, opts)
+	i.IntrospectionRequest = er
+	if err != nil {
+		return nil, err
+	}
+	if er.Response.StatusCode != http.StatusOK {
+		return nil, tokenRequestError("Introspection", er.Response, body)
+	}
+
+	var ret map[string]interface{}
+	err = json.Unmarshal(body, &ret)
+	if err != nil {
+		return nil, err
+	}
+	i.Introspection = ret
+	return ret, nil
+}
+
+// TokenActive - true if the introspection response indicates the token is active
+func TokenActive(introspection map[string]interface{}) bool {
+	active, ok := introspection[ActiveParam].(bool)
+	return ok && active
+}
+
+func (i *FlowInstance) tokenEndpointRequest(ctx context.Context, endpoint string, opts TokenRequestOptions) (*ExchangeRequest, []byte, error) {
+	v := url.Values{TokenParam: {opts.Token}}
+	if opts.TokenTypeHint != "" {
+		v.Set(TokenTypeHintParam, opts.TokenTypeHint)
+	}
+
+	conf := i.ClientConfig()
+	if opts.Unauthenticated {
+		conf = &oauth2.Config{}
+	}
+	req, err := newClientAuthRequest(ctx, conf, endpoint, v)
+	if err != nil {
+		return nil, nil, err
+	}
+	return doBackChannelRequest(nil, req)
+}
+
+func tokenRequestError(name string, resp *http.Response, body []byte) error {
+	return fmt.Errorf("%s request failed: %s %s", name, resp.Status, getErrorCodeFromBody(body))
+}
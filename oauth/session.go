@@ -0,0 +1,33 @@
+package oauth
+
+import (
+	"net/url"
+	"regexp"
+
+	"github.com/morganc3/KOAuth/config"
+)
+
+// default pattern of login page URLs, used if no login_url_pattern is configured
+var defaultLoginURLPattern = regexp.MustCompile(`(?i)(log-?in|sign-?in|authenticate)`)
+
+// SessionLost - true if the flow indicates the browser session at the authorization
+// server was lost. This is the case if the authorization server redirected with a
+// login_required or interaction_required error, or the flow timed out on a login page
+func (i *FlowInstance) SessionLost() bool {
+	if i.RedirectedToURL != nil && i.RedirectedToURL.String() != "" {
+		code := i.ResponseParameter(ErrorParam)
+		return code == LoginRequiredError || code == InteractionRequiredError
+	}
+	return isLoginPage(i.LastURL)
+}
+
+func isLoginPage(u *url.URL) bool {
+	if u == nil || u.Host == "" {
+		return false
+	}
+	pattern := config.OAuthConfig.LoginURLPattern
+	if pattern == nil {
+		pattern = defaultLoginURLPattern
+	}
+	return pattern.MatchString(u.String())
+}
@@ -0,0 +1,31 @@
+package oauth
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionLost(t *testing.T) {
+	parse := func(s string) *url.URL {
+		u, _ := url.Parse(s)
+		return u
+	}
+
+	i := FlowInstance{RedirectedToURL: parse("https://example.com/cb?error=login_required")}
+	assert.True(t, i.SessionLost())
+
+	i = FlowInstance{RedirectedToURL: parse("https://example.com/cb#error=interaction_required")}
+	assert.True(t, i.SessionLost())
+
+	i = FlowInstance{RedirectedToURL: parse("https://example.com/cb?error=access_denied")}
+	assert.False(t, i.SessionLost())
+
+	// timed out on a login page
+	i = FlowInstance{RedirectedToURL: new(url.URL), LastURL: parse("https://as.example.com/u/login?state=x")}
+	assert.True(t, i.SessionLost())
+
+	i = FlowInstance{RedirectedToURL: new(url.URL), LastURL: parse("https://as.example.com/consent")}
+	assert.False(t, i.SessionLost())
+}
@@ -1,7 +1,7 @@
 package oauth
 
 import (
-	"log"
+	"log/slog"
 	"net/url"
 )
 
@@ -29,8 +29,7 @@ func AddQueryParameter(u *url.URL, key, value string) {
 func GetFragmentParameterAll(u *url.URL, key string) []string {
 	values, err := url.ParseQuery(u.Fragment)
 	if err != nil {
-		log.Println("Error: Could not parse Fragment params")
-		log.Println(err)
+		slog.Warn("Could not parse fragment parameters", "err", err)
 		return values[key]
 	}
 	return values[key]
@@ -40,8 +39,7 @@ func GetFragmentParameterAll(u *url.URL, key string) []string {
 func GetFragmentParameterFirst(u *url.URL, key string) string {
 	values, err := url.ParseQuery(u.Fragment)
 	if err != nil {
-		log.Println("Error: Could not parse Fragment params")
-		log.Println(err)
+		slog.Warn("Could not parse fragment parameters", "err", err)
 		return ""
 	}
 	return values.Get(key)